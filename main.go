@@ -53,7 +53,7 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.selectedFile = path
 
 		// Launch the main app with the selected file
-		appModel, err := app.New(path)
+		appModel, err := app.New(path, app.Options{})
 		if err != nil {
 			m.err = err
 			return m, tea.Batch(cmd, clearErrorAfter(2*time.Second))
@@ -17,7 +17,25 @@ func seed(db *bolt.DB) error {
 		if err != nil {
 			return err
 		}
-		return nil
+
+		// Nested buckets: users/42/sessions
+		users, err := tx.CreateBucketIfNotExists([]byte("users"))
+		if err != nil {
+			return err
+		}
+		user, err := users.CreateBucketIfNotExists([]byte("42"))
+		if err != nil {
+			return err
+		}
+		err = user.Put([]byte("name"), []byte("alice"))
+		if err != nil {
+			return err
+		}
+		sessions, err := user.CreateBucketIfNotExists([]byte("sessions"))
+		if err != nil {
+			return err
+		}
+		return sessions.Put([]byte("s1"), []byte("active"))
 	})
 }
 
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// checkSizeLimit is the largest file checked when it is opened, as the
+// check reads every page; larger files can be checked with the check command
+const checkSizeLimit = 256 << 20
+
+// checkedMsg carries the result of the integrity check of the database
+type checkedMsg struct {
+	report bolt.CheckReport
+}
+
+// checkDB verifies the pages of the database in the background. Snapshots
+// are checked when they are taken.
+func (m *Model) checkDB() tea.Cmd {
+	m.checkReport = nil
+	if m.snapshot != nil {
+		return nil
+	}
+	if info, err := os.Stat(m.db.Path); err != nil || info.Size() > checkSizeLimit {
+		return nil
+	}
+	db := m.db
+	return func() tea.Msg {
+		report, err := db.Check()
+		if err != nil {
+			return fmt.Errorf("could not check the database: %v", err)
+		}
+		return checkedMsg{report}
+	}
+}
+
+// checkBanner warns that the database failed the integrity check
+func (m *Model) checkBanner() string {
+	report := m.checkReport
+	return fmt.Sprintf("%s %d problems found by the integrity check, first: %v\nRun 'bolt-tui check %s' for the full list",
+		m.styles.Badge.Render("CORRUPT"), report.Count, report.Problems[0], m.path)
+}
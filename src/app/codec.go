@@ -0,0 +1,99 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// previewDecodeLimit is the largest value decoded for the table preview
+const previewDecodeLimit = 64 << 10
+
+// pathKey identifies a bucket path in maps. Bucket names may contain "/", so
+// the parts are joined with a byte that is unlikely to appear in them.
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// decodeWith decodes value with the codec chosen for its bucket, or the
+// detected one when the bucket has none. With wire set, binary values that
+// no codec detects are decoded as protobuf wire format when they parse as
+// one. It returns a nil codec for values that are best shown as stored: JSON
+// and raw values keep their formatting and key order that way.
+func decodeWith(chosen codec.Codec, value []byte, wire bool) (codec.Codec, any, error) {
+	c := chosen
+	if c == nil {
+		c = codec.Detect(value)
+		if c == codec.Raw && wire && !codec.IsText(value) {
+			if tree, err := codec.ProtoWire.Decode(value); err == nil {
+				return codec.ProtoWire, tree, nil
+			}
+		}
+	}
+	if c == codec.Raw || c == codec.JSON {
+		return nil, nil, nil
+	}
+	tree, err := c.Decode(value)
+	if err != nil {
+		return c, nil, fmt.Errorf("could not decode value as %s: %v", c.Name(), err)
+	}
+	return c, tree, nil
+}
+
+// decodeValue decodes a value of the current bucket
+func (m *Model) decodeValue(value []byte) (codec.Codec, any, error) {
+	return decodeWith(m.codecs[pathKey(m.bucketPath)], value, m.wireFallback)
+}
+
+// checkRoundTrip returns an error when a decoded value would not be saved
+// back as the same bytes after going through the editor unchanged, e.g. when
+// it holds tags or timestamps that are shown as strings. Editing such a value
+// would silently change the types of the parts that were not edited.
+func checkRoundTrip(c codec.Codec, tree any, value []byte) error {
+	parsed, err := codec.Parse(codec.Format(tree, "  "))
+	if err == nil {
+		var encoded []byte
+		if encoded, err = c.Encode(parsed); err == nil && !bytes.Equal(encoded, value) {
+			err = fmt.Errorf("it would be saved as different bytes")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("value cannot be edited as %s without changing it (%v), press c to switch to another codec", c.Name(), err)
+	}
+	return nil
+}
+
+// codecList returns the registered codecs followed by the configured ones
+// that are not registered, such as protobuf message types
+func codecList(configured map[string]codec.Codec) []codec.Codec {
+	var list []codec.Codec
+	for _, name := range codec.Names() {
+		c, _ := codec.Lookup(name)
+		list = append(list, c)
+	}
+	var extra []codec.Codec
+	for _, c := range configured {
+		if _, err := codec.Lookup(c.Name()); err != nil && !slices.Contains(extra, c) {
+			extra = append(extra, c)
+		}
+	}
+	slices.SortFunc(extra, func(a, b codec.Codec) int { return strings.Compare(a.Name(), b.Name()) })
+	return append(list, extra...)
+}
+
+// cycleCodec switches the current bucket to the next codec, going back to
+// detecting the codec of each value after the last one
+func (m *Model) cycleCodec() {
+	next := 0
+	if c := m.codecs[pathKey(m.bucketPath)]; c != nil {
+		next = slices.Index(m.codecList, c) + 1
+	}
+	if next == len(m.codecList) {
+		delete(m.codecs, pathKey(m.bucketPath))
+		return
+	}
+	m.codecs[pathKey(m.bucketPath)] = m.codecList[next]
+}
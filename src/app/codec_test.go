@@ -0,0 +1,74 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/codec"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeWithWireFallback(t *testing.T) {
+	var message []byte
+	message = protowire.AppendTag(message, 1, protowire.VarintType)
+	message = protowire.AppendVarint(message, 150)
+	message = protowire.AppendTag(message, 2, protowire.BytesType)
+	message = protowire.AppendString(message, "alice")
+
+	tests := []struct {
+		name   string
+		chosen codec.Codec
+		value  []byte
+		wire   bool
+		want   codec.Codec
+	}{
+		{"message", nil, message, true, codec.ProtoWire},
+		{"message without fallback", nil, message, false, nil},
+		{"text", nil, []byte("plain text"), true, nil},
+		{"JSON", nil, []byte(`{"a": 1}`), true, nil},
+		{"not a message", nil, []byte{0xff, 0xff, 0xff}, true, nil},
+		{"chosen codec", codec.Hex, message, true, codec.Hex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, err := decodeWith(tt.chosen, tt.value, tt.wire)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != tt.want {
+				t.Errorf("codec = %v, want %v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	msgpack, err := codec.MessagePack.Encode(map[string]any{"id": int64(1), "name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// {"t": 0("2020-01-01T00:00:00Z")}, a timestamp the tree shows as a
+	// plain string
+	tagged := append([]byte{0xa1, 0x61, 't', 0xc0, 0x74}, "2020-01-01T00:00:00Z"...)
+
+	tests := []struct {
+		name    string
+		codec   codec.Codec
+		value   []byte
+		wantErr bool
+	}{
+		{"msgpack", codec.MessagePack, msgpack, false},
+		{"cbor timestamp", codec.CBOR, tagged, true},
+		{"hex", codec.Hex, []byte{0, 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := tt.codec.Decode(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := checkRoundTrip(tt.codec, tree, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
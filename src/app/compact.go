@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// compactProgressMsg reports how far a compaction got
+type compactProgressMsg struct {
+	items int
+	bytes int64
+	next  tea.Cmd // Waits for the following update
+}
+
+// compactDoneMsg carries the outcome of a compaction
+type compactDoneMsg struct {
+	result bolt.CompactResult
+	err    error
+}
+
+// openCompact asks where to write the compacted copy of the database
+func (m *Model) openCompact() tea.Cmd {
+	m.state = stateCompact
+	m.textInput.SetValue(m.path + ".compact")
+	m.textInput.CursorEnd()
+	return textinput.Blink
+}
+
+// startCompact copies the database into the chosen file in the background,
+// streaming progress back through a channel. The database itself is only
+// read, so compacting works in read-only mode and on snapshots too.
+func (m *Model) startCompact() tea.Cmd {
+	dst := strings.TrimSpace(m.textInput.Value())
+	if dst == "" {
+		return nil
+	}
+	m.err = nil
+	m.state = stateCompacting
+	m.compacting = true
+	m.compactDst = dst
+	m.compactItems, m.compactBytes = 0, 0
+	m.compactResult = nil
+
+	updates := make(chan tea.Msg, 1)
+	db := m.db
+	go func() {
+		defer close(updates)
+		result, err := db.Compact(dst, bolt.CompactOptions{
+			Progress: func(items int, bytes int64) {
+				// Skip updates while the UI has not caught up with the last one
+				select {
+				case updates <- compactProgressMsg{items: items, bytes: bytes}:
+				default:
+				}
+			},
+		})
+		updates <- compactDoneMsg{result, err}
+	}()
+	return waitForCompact(updates)
+}
+
+// waitForCompact blocks for the next update of a running compaction
+func waitForCompact(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg := <-updates
+		if progress, ok := msg.(compactProgressMsg); ok {
+			progress.next = waitForCompact(updates)
+			return progress
+		}
+		return msg
+	}
+}
+
+// applyCompact shows the outcome of a compaction
+func (m *Model) applyCompact(msg compactDoneMsg) {
+	m.compacting = false
+	if msg.err != nil {
+		m.err = msg.err
+		m.state = stateBuckets
+		return
+	}
+	m.compactResult = &msg.result
+}
+
+// compactView renders the destination prompt, the progress and the outcome
+// of a compaction
+func (m *Model) compactView() string {
+	var s strings.Builder
+	switch {
+	case m.state == stateCompact:
+		s.WriteString("Compact into a new file:\n\n")
+		s.WriteString(m.textInput.View())
+		s.WriteString("\n\n" + m.styles.Help.Render("The file must not exist. The database itself is left untouched."))
+	case m.compacting:
+		s.WriteString(fmt.Sprintf("Compacting into %s…\n\n", m.compactDst))
+		s.WriteString(fmt.Sprintf("Copied %d keys and buckets (%s)", m.compactItems, bolt.FormatSize(int(m.compactBytes))))
+	case m.compactResult != nil:
+		s.WriteString(fmt.Sprintf("Compacted %d keys and buckets into %s:\n\n", m.compactResult.Items, m.compactDst))
+		s.WriteString(m.compactResult.String())
+		s.WriteString("\n\n" + m.styles.Help.Render("Replace the database with the new file once no process has it open. esc: back"))
+	}
+	return s.String()
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/diff"
+)
+
+const diffContext = 3 // Unchanged lines shown around each change
+
+// renderDiff renders a diff with -/+ markers, collapsing long runs of
+// unchanged lines down to the context around each change
+func renderDiff(lines []diff.Line, st Styles) string {
+	show := diff.Context(lines, diffContext)
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		if !show[i] {
+			// Collapse the run of hidden lines
+			j := i
+			for j < len(lines) && !show[j] {
+				j++
+			}
+			out = append(out, st.Help.Render(fmt.Sprintf("  … %d unchanged lines", j-i)))
+			i = j - 1
+			continue
+		}
+		switch lines[i].Op {
+		case diff.Delete:
+			out = append(out, st.DiffDelete.Render("- "+lines[i].Text))
+		case diff.Insert:
+			out = append(out, st.DiffInsert.Render("+ "+lines[i].Text))
+		default:
+			out = append(out, "  "+lines[i].Text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,330 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/diff"
+)
+
+const diffViewLimit = 10000 // Changes listed before the comparison stops
+
+// DiffModel shows the differences between two databases: the list of changed
+// buckets and keys, and the old and new value of the selected one side by
+// side
+type DiffModel struct {
+	pathA, pathB string
+	a, b         *bolt.DB
+	changes      []bolt.Change // Without their values, which are loaded when shown
+	counts       [3]int        // Changes of each kind
+	more         bool          // The comparison stopped at diffViewLimit
+	shown        int           // Change shown in the panes, -1 for none
+
+	table         table.Model
+	left, right   viewport.Model
+	width, height int
+	keyMap        KeyMap
+	styles        Styles
+	err           error
+}
+
+var errDiffLimit = fmt.Errorf("stopped after %d changes", diffViewLimit)
+
+// NewDiff opens two databases read-only and compares them
+func NewDiff(pathA, pathB string, timeout time.Duration) (*DiffModel, error) {
+	m := &DiffModel{pathA: pathA, pathB: pathB, shown: -1, keyMap: DefaultKeyMap(), styles: DefaultStyles()}
+	m.a = &bolt.DB{Path: pathA, ReadOnly: true, Timeout: timeout}
+	if err := m.a.Open(); err != nil {
+		return nil, err
+	}
+	m.b = &bolt.DB{Path: pathB, ReadOnly: true, Timeout: timeout}
+	if err := m.b.Open(); err != nil {
+		m.a.Close()
+		return nil, err
+	}
+
+	var rows []table.Row
+	err := bolt.Diff(m.a, m.b, func(c bolt.Change) error {
+		if len(m.changes) == diffViewLimit {
+			return errDiffLimit
+		}
+		m.counts[c.Kind]++
+		m.changes = append(m.changes, bolt.Change{
+			Kind:     c.Kind,
+			Path:     append([]string{}, c.Path...),
+			Key:      append([]byte{}, c.Key...),
+			IsBucket: c.IsBucket,
+		})
+		rows = append(rows, table.Row{changeKind(c), displayPath(c.Path), displayBytes(c.Key)})
+		return nil
+	})
+	if err == errDiffLimit {
+		m.more = true
+	} else if err != nil {
+		m.Close()
+		return nil, fmt.Errorf("could not compare %s and %s: %v", pathA, pathB, err)
+	}
+
+	m.table = table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Change", Width: 16},
+			{Title: "Bucket", Width: 20},
+			{Title: "Key", Width: 30},
+		}),
+		table.WithRows(rows),
+		table.WithFocused(true),
+	)
+	m.table.SetStyles(table.Styles{
+		Header:   m.styles.Header,
+		Cell:     m.styles.Cell,
+		Selected: m.styles.Selected,
+	})
+	return m, nil
+}
+
+// changeKind describes a change in the list
+func changeKind(c bolt.Change) string {
+	what := "key"
+	if c.IsBucket {
+		what = "bucket"
+	}
+	switch c.Kind {
+	case bolt.Added:
+		return "+ " + what + " added"
+	case bolt.Removed:
+		return "- " + what + " removed"
+	default:
+		return "~ " + what + " changed"
+	}
+}
+
+// Close closes both databases
+func (m *DiffModel) Close() {
+	m.a.Close()
+	m.b.Close()
+}
+
+func (m *DiffModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *DiffModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Quit), key.Matches(msg, m.keyMap.Esc), msg.String() == "q":
+			return m, tea.Quit
+		case msg.Type == tea.KeyPgUp:
+			m.scroll(-m.left.Height)
+			return m, nil
+		case msg.Type == tea.KeyPgDown:
+			m.scroll(m.left.Height)
+			return m, nil
+		case msg.Type == tea.KeyLeft:
+			m.left.ScrollLeft(8)
+			m.right.ScrollLeft(8)
+			return m, nil
+		case msg.Type == tea.KeyRight:
+			m.left.ScrollRight(8)
+			m.right.ScrollRight(8)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	m.showSelected()
+	return m, cmd
+}
+
+// scroll moves both panes by n lines, keeping their lines aligned
+func (m *DiffModel) scroll(n int) {
+	offset := max(0, min(m.left.YOffset+n, max(m.left.TotalLineCount(), m.right.TotalLineCount())-m.left.Height))
+	m.left.SetYOffset(offset)
+	m.right.SetYOffset(offset)
+}
+
+// resize shares the window between the list of changes and the panes
+func (m *DiffModel) resize() {
+	m.table.SetHeight(max(3, (m.height-6)/3))
+	m.table.SetColumns([]table.Column{
+		{Title: "Change", Width: 16},
+		{Title: "Bucket", Width: max(10, (m.width-22)/3)},
+		{Title: "Key", Width: max(10, (m.width-22)*2/3)},
+	})
+	width := max(10, (m.width-3)/2)
+	height := max(3, m.height-m.table.Height()-8)
+	m.left = viewport.New(width, height)
+	m.right = viewport.New(width, height)
+	m.shown = -1
+	m.showSelected()
+}
+
+// showSelected loads the values of the selected change into the panes
+func (m *DiffModel) showSelected() {
+	i := m.table.Cursor()
+	if i < 0 || i >= len(m.changes) || i == m.shown {
+		return
+	}
+	m.shown = i
+	m.err = nil
+	left, right, err := m.sides(m.changes[i])
+	if err != nil {
+		m.err = err
+	}
+	m.left.SetContent(strings.Join(left, "\n"))
+	m.right.SetContent(strings.Join(right, "\n"))
+	m.left.GotoTop()
+	m.right.GotoTop()
+}
+
+// sides returns the lines of the old and new side of a change
+func (m *DiffModel) sides(c bolt.Change) ([]string, []string, error) {
+	absent := []string{m.styles.Help.Render("(absent)")}
+	if c.IsBucket {
+		bucket := []string{m.styles.Help.Render("(bucket)")}
+		if c.Kind == bolt.Added {
+			return absent, bucket, nil
+		}
+		return bucket, absent, nil
+	}
+
+	var old, new []byte
+	var err error
+	if c.Kind != bolt.Added {
+		if old, err = m.a.GetValue(c.Path, c.Key); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.Kind != bolt.Removed {
+		if new, err = m.b.GetValue(c.Path, c.Key); err != nil {
+			return nil, nil, err
+		}
+	}
+	old, new = old[:min(len(old), viewerLimit)], new[:min(len(new), viewerLimit)]
+
+	switch c.Kind {
+	case bolt.Added:
+		return absent, m.valueLines(new), nil
+	case bolt.Removed:
+		return m.valueLines(old), absent, nil
+	}
+	if codec.IsText(old) && codec.IsText(new) {
+		left, right := alignLines(diff.Lines(diff.Split(old), diff.Split(new)), m.styles)
+		return left, right, nil
+	}
+	left, right := alignHex(old, new, m.styles)
+	return left, right, nil
+}
+
+// valueLines returns the lines of a value on its own: text and JSON as in
+// a diff, anything else as a hex dump
+func (m *DiffModel) valueLines(v []byte) []string {
+	if codec.IsText(v) {
+		return diff.Split(v)
+	}
+	return strings.Split(hexDump(v, m.styles), "\n")
+}
+
+// alignLines lays out a line diff in two columns. Deleted lines are paired
+// with the inserted lines that replace them, and padded with blank lines
+// where there are fewer of one than the other.
+func alignLines(lines []diff.Line, st Styles) (left, right []string) {
+	for i := 0; i < len(lines); {
+		if lines[i].Op == diff.Equal {
+			left = append(left, lines[i].Text)
+			right = append(right, lines[i].Text)
+			i++
+			continue
+		}
+		var deleted, inserted []string
+		for ; i < len(lines) && lines[i].Op != diff.Equal; i++ {
+			if lines[i].Op == diff.Delete {
+				deleted = append(deleted, st.DiffDelete.Render(lines[i].Text))
+			} else {
+				inserted = append(inserted, st.DiffInsert.Render(lines[i].Text))
+			}
+		}
+		for j := 0; j < max(len(deleted), len(inserted)); j++ {
+			l, r := "", ""
+			if j < len(deleted) {
+				l = deleted[j]
+			}
+			if j < len(inserted) {
+				r = inserted[j]
+			}
+			left = append(left, l)
+			right = append(right, r)
+		}
+	}
+	return left, right
+}
+
+// alignHex lays out the hex dumps of two values in two columns, with the
+// lines holding differing bytes highlighted
+func alignHex(a, b []byte, st Styles) (left, right []string) {
+	la := strings.Split(hexDump(a, Styles{}), "\n")
+	lb := strings.Split(hexDump(b, Styles{}), "\n")
+	for i := 0; i < max(len(la), len(lb)); i++ {
+		from, to := i*16, (i+1)*16
+		same := string(a[min(from, len(a)):min(to, len(a))]) == string(b[min(from, len(b)):min(to, len(b))])
+		l, r := "", ""
+		if i < len(la) && len(a) > 0 {
+			l = la[i]
+			if !same {
+				l = st.DiffDelete.Render(l)
+			}
+		}
+		if i < len(lb) && len(b) > 0 {
+			r = lb[i]
+			if !same {
+				r = st.DiffInsert.Render(r)
+			}
+		}
+		left = append(left, l)
+		right = append(right, r)
+	}
+	return left, right
+}
+
+func (m *DiffModel) View() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("%s → %s: %d added, %d removed, %d changed",
+		m.pathA, m.pathB, m.counts[bolt.Added], m.counts[bolt.Removed], m.counts[bolt.Changed]))
+	if m.more {
+		s.WriteString(fmt.Sprintf(", %v", errDiffLimit))
+	}
+	s.WriteString("\n\n")
+	if len(m.changes) == 0 {
+		s.WriteString("The files have the same contents.\n\n")
+		s.WriteString(m.styles.Help.Render(" q/esc: quit "))
+		return s.String()
+	}
+
+	s.WriteString(m.table.View())
+	s.WriteString("\n\n")
+	c := m.changes[m.table.Cursor()]
+	s.WriteString(displayPath(append(append([]string{}, c.Path...), string(c.Key))))
+	s.WriteString("\n")
+	if m.err != nil {
+		s.WriteString(fmt.Sprintf("Error: %v\n", m.err))
+	}
+	divider := m.styles.Help.Render(strings.Repeat("│\n", max(0, m.left.Height-1)) + "│")
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.left.View(), " ", divider, " ", m.right.View()))
+	s.WriteString("\n")
+	s.WriteString(m.styles.Help.Render(" ↑/↓: select · pgup/pgdn: scroll · ←/→: scroll sideways · q/esc: quit "))
+	return s.String()
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/diff"
+)
+
+// editorMaxLines is the number of lines the textarea can hold
+const editorMaxLines = 10000
+
+// editValue opens the value of key in the multi-line editor
+func (m *Model) editValue(key []byte) tea.Cmd {
+	// Get the current value to populate the editor
+	value, err := m.getValue(m.bucketPath, key)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+
+	// Encoded values are edited in their decoded form, and binary values in
+	// their escaped form so they survive the round-trip through the editor
+	c, tree, err := m.decodeValue(value)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	if c != nil {
+		if err := checkRoundTrip(c, tree, value); err != nil {
+			m.err = err
+			return nil
+		}
+	}
+	content := string(value)
+	m.editCodec = c
+	m.valueEscaped = c == nil && !codec.IsText(value)
+	if c != nil {
+		content = codec.Format(tree, "  ")
+	} else if m.valueEscaped {
+		content = displayBytes(value)
+	}
+	if strings.Count(content, "\n") >= editorMaxLines {
+		m.err = fmt.Errorf("value has more than %d lines, too large for the editor", editorMaxLines)
+		return nil
+	}
+
+	// The editor replaces tabs and carriage returns as it is filled, so text
+	// holding them is edited in escaped form to be saved back unchanged
+	m.editor.SetValue(content)
+	if c == nil && !m.valueEscaped && m.editor.Value() != content {
+		m.valueEscaped = true
+		content = displayBytes(value)
+		m.editor.SetValue(content)
+	}
+
+	m.currentKey = key
+	m.editOriginal = value
+	m.editContent = content
+	m.resizeEditor()
+	m.state = stateEditValue
+	return m.editor.Focus()
+}
+
+// resizeEditor fits the editor to the window
+func (m *Model) resizeEditor() {
+	m.editor.SetWidth(max(20, m.width-6))
+	m.editor.SetHeight(max(5, m.height-14)) // Account for header, title, help, etc.
+}
+
+// editedValue returns the bytes the editor content stands for
+func (m *Model) editedValue() ([]byte, error) {
+	return encodeEdited(m.editCodec, m.valueEscaped, m.editor.Value())
+}
+
+// encodeEdited converts edited content back to a value: encoded with c when
+// it is set, parsed when the content is in escaped form, or as is otherwise
+func encodeEdited(c codec.Codec, escaped bool, content string) ([]byte, error) {
+	switch {
+	case c != nil:
+		tree, err := codec.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("value is not valid JSON for %s: %v", c.Name(), err)
+		}
+		return c.Encode(tree)
+	case escaped:
+		return parseBytes(content), nil
+	default:
+		return []byte(content), nil
+	}
+}
+
+// reviewEdit validates the edited value and shows the changes for
+// confirmation. Nothing is written when the value did not change.
+func (m *Model) reviewEdit() tea.Cmd {
+	if m.editor.Value() == m.editContent {
+		m.state = stateBuckets
+		return nil
+	}
+	value, err := m.editedValue()
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	if bytes.Equal(value, m.editOriginal) {
+		m.state = stateBuckets
+		return nil
+	}
+
+	// Keep JSON values valid, unless the value is deliberately emptied
+	if len(value) > 0 && json.Valid(m.editOriginal) && !json.Valid(value) {
+		var v any
+		err := json.Unmarshal(value, &v)
+		m.err = fmt.Errorf("value is no longer valid JSON: %v", err)
+		return nil
+	}
+
+	m.err = nil
+	m.editValueNew = value
+	m.state = stateConfirmEdit
+	m.viewer.Width = max(20, m.width-6)
+	m.viewer.Height = max(5, m.height-14)
+	before, after := m.editLines(m.editOriginal), m.editLines(value)
+	if m.editCodec != nil {
+		// Compare the decoded forms, the encoded ones are not readable
+		before, after = strings.Split(m.editContent, "\n"), strings.Split(m.editor.Value(), "\n")
+	}
+	m.viewer.SetContent(renderDiff(diff.Lines(before, after), m.styles))
+	m.viewer.GotoTop()
+	return nil
+}
+
+// editLines splits a value into lines the same way the editor shows it
+func (m *Model) editLines(value []byte) []string {
+	if m.valueEscaped {
+		return []string{displayBytes(value)}
+	}
+	return strings.Split(string(value), "\n")
+}
+
+// saveEdit writes the reviewed value
+func (m *Model) saveEdit() tea.Cmd {
+	err := m.makeEdit(edit{kind: editPut, path: m.bucketPath, key: m.currentKey,
+		old: m.editOriginal, new: m.editValueNew, created: m.editOriginal == nil})
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.state = stateBuckets
+	m.editOriginal, m.editValueNew = nil, nil
+	return m.reloadKeys()
+}
+
+// editorView renders the multi-line value editor
+func (m *Model) editorView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Edit value of key '%s' in bucket '%s':\n\n", displayBytes(m.currentKey), displayPath(m.bucketPath)))
+	if m.valueEscaped {
+		s.WriteString("Binary value: use a quoted string with escapes or 0x-prefixed hex\n\n")
+	} else if m.editCodec != nil {
+		s.WriteString(fmt.Sprintf("Decoded %s value, saved re-encoded\n\n", m.editCodec.Name()))
+	}
+	s.WriteString(m.editor.View())
+	s.WriteString("\n")
+	size := "invalid"
+	if value, err := m.editedValue(); err == nil {
+		size = fmt.Sprintf("%d bytes", len(value))
+	}
+	s.WriteString(m.styles.Help.Render(fmt.Sprintf(" %s · ctrl+s: review and save · esc: cancel ", size)))
+	return s.String()
+}
+
+// confirmEditView renders the changes of an edit waiting for confirmation
+func (m *Model) confirmEditView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Save changes to key '%s' in bucket '%s'? (%d → %d bytes)\n\n",
+		displayBytes(m.currentKey), displayPath(m.bucketPath), len(m.editOriginal), len(m.editValueNew)))
+	if len(m.editValueNew) == 0 {
+		s.WriteString("The value will be saved empty.\n\n")
+	}
+	s.WriteString(m.viewer.View())
+	s.WriteString("\n")
+	s.WriteString(m.styles.Help.Render(" enter: save · esc: back to editor "))
+	return s.String()
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEditValueKeepsWhitespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		escaped bool
+	}{
+		{"plain", "hello", false},
+		{"newlines", "a\nb\nc", false},
+		{"tabs", "a\tb", true},
+		{"crlf", "a\r\nb\r\n", true},
+		{"tabs and crlf", "a\tb\r\nc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openTestModel(t, map[string]string{"k": tt.value})
+			m.editValue([]byte("k"))
+			if m.err != nil {
+				t.Fatal(m.err)
+			}
+			if m.state != stateEditValue {
+				t.Fatalf("state = %v, want the editor", m.state)
+			}
+			if m.valueEscaped != tt.escaped {
+				t.Errorf("escaped = %v, want %v", m.valueEscaped, tt.escaped)
+			}
+			value, err := m.editedValue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(value, []byte(tt.value)) {
+				t.Errorf("unchanged editor gives %q, want %q", value, tt.value)
+			}
+		})
+	}
+}
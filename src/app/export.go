@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/dump"
+)
+
+// exportedMsg carries the outcome of an export
+type exportedMsg struct {
+	file  string
+	count int
+	err   error
+}
+
+// openExport asks where to export the current bucket, suggesting a file
+// named after it next to the database
+func (m *Model) openExport() tea.Cmd {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x80 && (r == '.' || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return r
+		}
+		return '_'
+	}, strings.Join(m.bucketPath, "."))
+	m.state = stateExport
+	m.exporting = false
+	m.exportCount = -1
+	m.textInput.SetValue(filepath.Join(filepath.Dir(m.path), name+".jsonl"))
+	m.textInput.CursorEnd()
+	return textinput.Blink
+}
+
+// startExport writes the current bucket to the chosen file in the
+// background: as a JSON array for .json files, and JSON Lines otherwise.
+// Values are decoded with the codec chosen for their bucket, if any.
+func (m *Model) startExport() tea.Cmd {
+	file := strings.TrimSpace(m.textInput.Value())
+	if file == "" {
+		return nil
+	}
+	if _, err := os.Stat(file); err == nil {
+		m.err = fmt.Errorf("%s already exists", file)
+		return nil
+	}
+	m.err = nil
+	m.exporting = true
+	m.exportFile = file
+
+	opts := dump.ExportOptions{Binary: dump.Base64}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		opts.Format = dump.JSON
+	}
+	codecs := m.codecs
+	opts.Codec = func(path []string, value []byte) codec.Codec {
+		return codecs[pathKey(path)]
+	}
+	db, path := m.db, append([]string{}, m.bucketPath...)
+	return func() tea.Msg {
+		f, err := os.Create(file)
+		if err != nil {
+			return exportedMsg{file: file, err: err}
+		}
+		count, err := dump.Export(db, path, f, opts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(file)
+		}
+		return exportedMsg{file, count, err}
+	}
+}
+
+// applyExport shows the outcome of an export
+func (m *Model) applyExport(msg exportedMsg) {
+	m.exporting = false
+	if msg.err != nil {
+		m.err = fmt.Errorf("could not export to %s: %v", msg.file, msg.err)
+		m.state = stateBuckets
+		return
+	}
+	m.exportCount = msg.count
+}
+
+// exportView renders the file prompt and the outcome of an export
+func (m *Model) exportView() string {
+	var s strings.Builder
+	switch {
+	case m.exporting:
+		s.WriteString(fmt.Sprintf("Exporting bucket '%s' to %s…", displayPath(m.bucketPath), m.exportFile))
+	case m.exportCount >= 0:
+		s.WriteString(fmt.Sprintf("Exported %d records of bucket '%s' to %s", m.exportCount, displayPath(m.bucketPath), m.exportFile))
+		s.WriteString("\n\n" + m.styles.Help.Render("esc: back"))
+	default:
+		s.WriteString(fmt.Sprintf("Export bucket '%s' to:\n\n", displayPath(m.bucketPath)))
+		s.WriteString(m.textInput.View())
+		s.WriteString("\n\n" + m.styles.Help.Render("JSON for .json files, JSON Lines otherwise. Values are decoded with the codec of their bucket."))
+	}
+	return s.String()
+}
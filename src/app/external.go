@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// externalEditMsg is sent when the external editor exits
+type externalEditMsg struct {
+	path     []string
+	key      []byte
+	original []byte
+	codec    codec.Codec // Codec the value was decoded with, nil when written as stored
+	content  []byte      // What was written to the file
+	file     string
+	err      error
+}
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, falling
+// back to vi. The variables may hold arguments, e.g. "code --wait".
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// openExternalEditor writes the value of key to a temp file and suspends the
+// program while the user's editor runs on it. Encoded values are written in
+// their decoded form.
+func (m *Model) openExternalEditor(key []byte) tea.Cmd {
+	value, err := m.getValue(m.bucketPath, key)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	c, tree, err := m.decodeValue(value)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	content := value
+	if c != nil {
+		if err := checkRoundTrip(c, tree, value); err != nil {
+			m.err = err
+			return nil
+		}
+		content = []byte(codec.Format(tree, "  ") + "\n")
+	}
+
+	// Give editors a hint about the content for syntax highlighting
+	ext := ".txt"
+	switch {
+	case c != nil, valueKind(value) == "JSON":
+		ext = ".json"
+	case valueKind(value) == "binary":
+		ext = ".bin"
+	}
+	f, err := os.CreateTemp("", "bolt-tui-*"+ext)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		m.err = err
+		return nil
+	}
+
+	args := append(editorCommand(), f.Name())
+	msg := externalEditMsg{path: m.bucketPath, key: key, original: value, codec: c, content: content, file: f.Name()}
+	return tea.ExecProcess(exec.Command(args[0], args[1:]...), func(err error) tea.Msg {
+		msg.err = err
+		return msg
+	})
+}
+
+// finishExternalEdit writes the edited file back if it changed, refusing to
+// overwrite a value that was modified while the editor was open
+func (m *Model) finishExternalEdit(msg externalEditMsg) tea.Cmd {
+	if msg.err != nil {
+		os.Remove(msg.file)
+		m.err = fmt.Errorf("editor failed: %v", msg.err)
+		return nil
+	}
+
+	content, err := os.ReadFile(msg.file)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	content = trimAddedNewline(msg.content, content)
+	if bytes.Equal(content, msg.content) {
+		os.Remove(msg.file)
+		return nil
+	}
+	value, err := encodeEdited(msg.codec, false, string(content))
+	if err != nil {
+		m.err = fmt.Errorf("%v; your changes are in %s", err, msg.file)
+		return nil
+	}
+
+	err = m.makeEdit(edit{kind: editPut, path: msg.path, key: msg.key, old: msg.original, new: value})
+	if errors.Is(err, bolt.ErrValueChanged) {
+		// Keep the file so the edits are not lost
+		m.err = fmt.Errorf("key '%s' was modified while editing, not saved; your changes are in %s", displayBytes(msg.key), msg.file)
+		return nil
+	}
+	if err != nil {
+		m.err = fmt.Errorf("%v; your changes are in %s", err, msg.file)
+		return nil
+	}
+	os.Remove(msg.file)
+	m.err = nil
+	if m.state == stateViewValue && bytes.Equal(m.viewKey, msg.key) {
+		return tea.Batch(m.openViewer(msg.key), m.reloadKeys())
+	}
+	return m.reloadKeys()
+}
+
+// trimAddedNewline drops the final newline most editors end a file with
+// when the content written for editing had none, so that a value is not
+// changed just by opening it
+func trimAddedNewline(written, edited []byte) []byte {
+	if bytes.HasSuffix(written, []byte("\n")) {
+		return edited
+	}
+	return bytes.TrimSuffix(edited, []byte("\n"))
+}
@@ -0,0 +1,27 @@
+package app
+
+import "testing"
+
+func TestTrimAddedNewline(t *testing.T) {
+	tests := []struct {
+		name    string
+		written string
+		edited  string
+		want    string
+	}{
+		{"newline added", "value", "value\n", "value"},
+		{"unchanged", "value", "value", "value"},
+		{"empty value", "", "\n", ""},
+		{"edited", "value", "other\n", "other"},
+		{"blank line added", "value", "value\n\n", "value\n"},
+		{"newline kept", "value\n", "value\n", "value\n"},
+		{"newline removed", "value\n", "value", "value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(trimAddedNewline([]byte(tt.written), []byte(tt.edited))); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,15 @@
 package app
 
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
 // Helper functions
 func min(a, b int) int {
 	if a < b {
@@ -14,3 +24,105 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+// equalPaths reports whether two bucket paths are the same
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// displayBytes renders raw bytes so that every key can be told apart and typed
+// back in. Printable text is shown as is, mostly printable data is shown as a
+// Go quoted string with escapes, and anything else is shown as 0x-prefixed hex.
+// Text that would itself look like one of the encoded forms is quoted.
+func displayBytes(b []byte) string {
+	s := string(b)
+	if isPrintable(b) && !strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "0x") {
+		return s
+	}
+	printable, total := 0, 0
+	if utf8.Valid(b) {
+		for _, r := range s {
+			if unicode.IsPrint(r) {
+				printable++
+			}
+			total++
+		}
+	} else {
+		for _, c := range b {
+			if c >= 0x20 && c < 0x7f {
+				printable++
+			}
+		}
+		total = len(b)
+	}
+	if printable*4 >= total*3 {
+		return strconv.Quote(s)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// parseBytes is the inverse of displayBytes: it accepts 0x-prefixed hex, a Go
+// quoted string, or plain text
+func parseBytes(s string) []byte {
+	if strings.HasPrefix(s, "0x") && len(s) > 2 {
+		if b, err := hex.DecodeString(s[2:]); err == nil {
+			return b
+		}
+	}
+	if strings.HasPrefix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return []byte(u)
+		}
+	}
+	return []byte(s)
+}
+
+// isPrintable reports whether b is valid UTF-8 made only of printable runes
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// displayPath renders a bucket path with each bucket name made displayable
+func displayPath(path []string) string {
+	parts := make([]string, len(path))
+	for i, name := range path {
+		parts[i] = displayBytes([]byte(name))
+	}
+	return strings.Join(parts, "/")
+}
+
+// parseFilter turns filter input into a key range: "from..to" is the range
+// [from, to), "from.." and "..to" are open ended, and anything else is a key
+// prefix. Each bound accepts the same forms as parseBytes.
+func parseFilter(s string) bolt.Range {
+	if s == "" {
+		return bolt.Range{}
+	}
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		var r bolt.Range
+		if from != "" {
+			r.From = parseBytes(from)
+		}
+		if to != "" {
+			r.To = parseBytes(to)
+		}
+		return r
+	}
+	return bolt.Range{Prefix: parseBytes(s)}
+}
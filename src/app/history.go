@@ -0,0 +1,308 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+const (
+	historyLimit   = 100      // Edits kept for undo
+	undoBucketSize = 64 << 20 // Bytes of a deleted bucket kept to undo the delete
+)
+
+type editKind int
+
+const (
+	editPut editKind = iota
+	editDelete
+	editRenameKey
+	editCreateBucket
+	editDeleteBucket
+	editRenameBucket
+)
+
+// edit is a change made to the database, with the state it replaced so that
+// it can be undone
+type edit struct {
+	kind     editKind
+	path     []string // Bucket holding the key, or the bucket itself
+	key      []byte
+	old, new []byte     // Values before and after
+	created  bool       // The put created the key
+	newKey   []byte     // New name of a renamed key
+	newName  string     // New name of a renamed bucket
+	contents *bolt.Tree // Contents of a deleted bucket, nil when too large to keep
+}
+
+func (e edit) String() string {
+	name := displayPath(append(append([]string{}, e.path...), string(e.key)))
+	switch e.kind {
+	case editPut:
+		if e.created {
+			return fmt.Sprintf("create key %s", name)
+		}
+		return fmt.Sprintf("edit %s (%d → %d bytes)", name, len(e.old), len(e.new))
+	case editDelete:
+		return fmt.Sprintf("delete key %s", name)
+	case editRenameKey:
+		return fmt.Sprintf("rename key %s to %s", name, displayBytes(e.newKey))
+	case editCreateBucket:
+		return fmt.Sprintf("create bucket %s", displayPath(e.path))
+	case editDeleteBucket:
+		return fmt.Sprintf("delete bucket %s", displayPath(e.path))
+	default:
+		return fmt.Sprintf("rename bucket %s to %s", displayPath(e.path), displayBytes([]byte(e.newName)))
+	}
+}
+
+// renamed returns the path of a renamed bucket after the rename
+func (e edit) renamed() []string {
+	path := append([]string{}, e.path...)
+	path[len(path)-1] = e.newName
+	return path
+}
+
+// apply makes the edit, again when redoing it. Keys are only written while
+// they hold the value the edit expects.
+func (e edit) apply(db *bolt.DB) error {
+	switch e.kind {
+	case editPut:
+		if e.created {
+			return db.PutNewValue(e.path, e.key, e.new)
+		}
+		return db.CompareAndPut(e.path, e.key, e.old, e.new)
+	case editDelete:
+		return db.CompareAndDelete(e.path, e.key, e.old)
+	case editRenameKey:
+		return db.RenameKey(e.path, e.key, e.newKey)
+	case editCreateBucket:
+		return db.CreateBucket(e.path)
+	case editDeleteBucket:
+		// Without contents the delete cannot be undone, so it is only ever
+		// made the first time
+		if e.contents == nil {
+			return db.DeleteBucket(e.path)
+		}
+		return db.CompareAndDeleteBucket(e.path, e.contents)
+	default:
+		return db.RenameBucket(e.path, e.newName)
+	}
+}
+
+// revert undoes the edit. Like apply, it refuses to overwrite changes made
+// to the key or bucket since the edit.
+func (e edit) revert(db *bolt.DB) error {
+	switch e.kind {
+	case editPut:
+		if e.created {
+			return db.CompareAndDelete(e.path, e.key, e.new)
+		}
+		return db.CompareAndPut(e.path, e.key, e.new, e.old)
+	case editDelete:
+		return db.PutNewValue(e.path, e.key, e.old)
+	case editRenameKey:
+		return db.RenameKey(e.path, e.newKey, e.key)
+	case editCreateBucket:
+		return db.DeleteEmptyBucket(e.path)
+	case editDeleteBucket:
+		if e.contents == nil {
+			return fmt.Errorf("it held more than %s, too much to keep", bolt.FormatSize(undoBucketSize))
+		}
+		return db.WriteTree(e.path, e.contents)
+	default:
+		return db.RenameBucket(e.renamed(), e.path[len(e.path)-1])
+	}
+}
+
+// record adds an edit that was just made to the history, which drops the
+// edits that were undone
+func (m *Model) record(e edit) {
+	e.path = append([]string{}, e.path...)
+	m.undoStack = append(m.undoStack, e)
+	if len(m.undoStack) > historyLimit {
+		m.undoStack = m.undoStack[len(m.undoStack)-historyLimit:]
+	}
+	m.redoStack = nil
+}
+
+// deleteValue deletes a key, keeping its value to undo the delete
+func (m *Model) deleteValue(path []string, key []byte) error {
+	old, err := m.getValue(path, key)
+	if err != nil || old == nil {
+		return err
+	}
+	return m.makeEdit(edit{kind: editDelete, path: path, key: key, old: old})
+}
+
+// deleteBucket deletes a bucket, keeping its contents to undo the delete
+// unless they are too large
+func (m *Model) deleteBucket(path []string) error {
+	if m.staging {
+		return m.stage(edit{kind: editDeleteBucket, path: path})
+	}
+	contents, err := m.db.ReadTree(path, undoBucketSize)
+	if err != nil && !errors.Is(err, bolt.ErrTooLarge) {
+		return err
+	}
+	return m.makeEdit(edit{kind: editDeleteBucket, path: path, contents: contents})
+}
+
+// createBucket creates a bucket, recording it unless it already existed
+func (m *Model) createBucket(path []string) error {
+	if m.staging {
+		return m.stage(edit{kind: editCreateBucket, path: path})
+	}
+	siblings, err := m.db.GetBuckets(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	if slices.Contains(siblings, path[len(path)-1]) {
+		return nil
+	}
+	return m.makeEdit(edit{kind: editCreateBucket, path: path})
+}
+
+// createKey creates a key with an empty value, keeping the value it replaces
+// when it already existed
+func (m *Model) createKey(path []string, key []byte) error {
+	old, err := m.getValue(path, key)
+	if err != nil {
+		return err
+	}
+	return m.makeEdit(edit{kind: editPut, path: path, key: key, old: old, new: []byte{}, created: old == nil})
+}
+
+// makeEdit makes an edit whose prior state is already known, and records it.
+// In staging mode the edit is staged instead.
+func (m *Model) makeEdit(e edit) error {
+	if m.staging {
+		return m.stage(e)
+	}
+	if err := e.apply(m.db); err != nil {
+		return err
+	}
+	m.record(e)
+	return nil
+}
+
+// undo reverts the last edit and shows where it happened
+func (m *Model) undo() tea.Cmd {
+	if len(m.undoStack) == 0 {
+		m.err = fmt.Errorf("nothing to undo")
+		return nil
+	}
+	e := m.undoStack[len(m.undoStack)-1]
+	if err := e.revert(m.db); err != nil {
+		m.err = fmt.Errorf("could not undo %s: %v", e, err)
+		return nil
+	}
+	m.err = nil
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.redoStack = append(m.redoStack, e)
+	return m.revealEdit(e, true)
+}
+
+// redo makes the last undone edit again and shows where it happened
+func (m *Model) redo() tea.Cmd {
+	if len(m.redoStack) == 0 {
+		m.err = fmt.Errorf("nothing to redo")
+		return nil
+	}
+	e := m.redoStack[len(m.redoStack)-1]
+	if err := e.apply(m.db); err != nil {
+		m.err = fmt.Errorf("could not redo %s: %v", e, err)
+		return nil
+	}
+	m.err = nil
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.undoStack = append(m.undoStack, e)
+	return m.revealEdit(e, false)
+}
+
+// revealEdit reloads the table after an undo or redo, opening the bucket
+// the edit happened in with the cursor on its key or nested bucket
+func (m *Model) revealEdit(e edit, undone bool) tea.Cmd {
+	if m.state == stateHistory {
+		m.viewer.SetContent(m.renderHistory())
+	}
+
+	// Keys and nested buckets are shown in their bucket, under the name they
+	// have once the edit is made or reverted
+	parent, at := e.path, e.key
+	switch e.kind {
+	case editRenameKey:
+		if !undone {
+			at = e.newKey
+		}
+	case editCreateBucket, editDeleteBucket, editRenameBucket:
+		parent, at = e.path[:len(e.path)-1], []byte(e.path[len(e.path)-1])
+		if e.kind == editRenameBucket && !undone {
+			at = []byte(e.newName)
+		}
+	}
+
+	// Top-level buckets are tabs, which are reloaded in case they changed
+	if len(parent) == 0 {
+		exists := e.kind == editRenameBucket || (e.kind == editCreateBucket) != undone
+		m.bucketPath = nil
+		if exists {
+			m.newlyCreatedBucket = string(at)
+		}
+		return m.loadBuckets
+	}
+	for i, bucket := range m.buckets {
+		if bucket == parent[0] {
+			m.activeTab = i
+		}
+	}
+	return m.openBucketAt(parent, at)
+}
+
+// openHistory shows the edits of the session
+func (m *Model) openHistory() tea.Cmd {
+	m.state = stateHistory
+	m.viewer.Width = max(20, m.width-6)
+	m.viewer.Height = max(5, m.height-12)
+	m.viewer.SetContent(m.renderHistory())
+	m.viewer.GotoTop()
+	return nil
+}
+
+// renderHistory lists the edits, the most recent first, with the undone
+// ones that can be redone above the others
+func (m *Model) renderHistory() string {
+	if len(m.undoStack) == 0 && len(m.redoStack) == 0 {
+		return m.styles.Help.Render("No edits yet.")
+	}
+	var lines []string
+	for _, e := range m.redoStack {
+		lines = append(lines, m.styles.Help.Render("  ↷ "+e.String()+" (undone)"))
+	}
+	for i := len(m.undoStack) - 1; i >= 0; i-- {
+		marker := "    "
+		if i == len(m.undoStack)-1 {
+			marker = "  ▸ "
+		}
+		lines = append(lines, marker+m.undoStack[i].String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// historyView renders the list of edits
+func (m *Model) historyView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Edits of this session (%d, up to %d kept):\n\n", len(m.undoStack)+len(m.redoStack), historyLimit))
+	s.WriteString(m.viewer.View())
+	s.WriteString("\n")
+	if m.staging {
+		s.WriteString(m.styles.Help.Render(" u: unstage the last staged change · ↑/↓ scroll · esc: back "))
+	} else {
+		s.WriteString(m.styles.Help.Render(" u: undo ▸ · ctrl+r: redo ↷ · ↑/↓ scroll · esc: back "))
+	}
+	return s.String()
+}
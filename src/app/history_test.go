@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+func TestEditConflicts(t *testing.T) {
+	a, sub := []string{"a"}, []string{"a", "sub"}
+	put := func(path []string, key, value string) func(*bolt.DB) error {
+		return func(db *bolt.DB) error { return db.PutValue(path, []byte(key), []byte(value)) }
+	}
+	value := func(db *bolt.DB, path []string, key string) string {
+		v, err := db.GetValue(path, []byte(key))
+		if err != nil || v == nil {
+			return "<none>"
+		}
+		return string(v)
+	}
+	buckets := func(db *bolt.DB) int {
+		names, _ := db.GetBuckets(a)
+		return len(names)
+	}
+	contents := &bolt.Tree{Entries: []bolt.TreeEntry{{Key: []byte("x"), Value: []byte("1")}}}
+
+	tests := []struct {
+		name      string
+		edit      edit
+		redo      bool                 // Test the redo of the edit rather than its undo
+		meanwhile func(*bolt.DB) error // Change made after the edit, or its undo for redos
+		wantErr   error                // bolt.ErrValueChanged, or any error when errAny
+		errAny    bool
+		check     func(*bolt.DB) bool
+	}{
+		{
+			name:  "undo edit",
+			edit:  edit{kind: editPut, path: a, key: []byte("k"), old: []byte("v"), new: []byte("w")},
+			check: func(db *bolt.DB) bool { return value(db, a, "k") == "v" },
+		},
+		{
+			name:      "undo edit changed since",
+			edit:      edit{kind: editPut, path: a, key: []byte("k"), old: []byte("v"), new: []byte("w")},
+			meanwhile: put(a, "k", "other"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, a, "k") == "other" },
+		},
+		{
+			name:  "undo create key",
+			edit:  edit{kind: editPut, path: a, key: []byte("n"), new: []byte{}, created: true},
+			check: func(db *bolt.DB) bool { return value(db, a, "n") == "<none>" },
+		},
+		{
+			name:      "undo create key changed since",
+			edit:      edit{kind: editPut, path: a, key: []byte("n"), new: []byte{}, created: true},
+			meanwhile: put(a, "n", "other"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, a, "n") == "other" },
+		},
+		{
+			name:  "undo delete key",
+			edit:  edit{kind: editDelete, path: a, key: []byte("k"), old: []byte("v")},
+			check: func(db *bolt.DB) bool { return value(db, a, "k") == "v" },
+		},
+		{
+			name:      "undo delete key created since",
+			edit:      edit{kind: editDelete, path: a, key: []byte("k"), old: []byte("v")},
+			meanwhile: put(a, "k", "other"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, a, "k") == "other" },
+		},
+		{
+			name:      "redo delete key changed since",
+			edit:      edit{kind: editDelete, path: a, key: []byte("k"), old: []byte("v")},
+			redo:      true,
+			meanwhile: put(a, "k", "other"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, a, "k") == "other" },
+		},
+		{
+			name:  "undo create bucket",
+			edit:  edit{kind: editCreateBucket, path: sub},
+			check: func(db *bolt.DB) bool { return buckets(db) == 0 },
+		},
+		{
+			name:      "undo create bucket filled since",
+			edit:      edit{kind: editCreateBucket, path: sub},
+			meanwhile: put(sub, "x", "1"),
+			errAny:    true,
+			check:     func(db *bolt.DB) bool { return value(db, sub, "x") == "1" },
+		},
+		{
+			name:  "redo delete bucket",
+			edit:  edit{kind: editDeleteBucket, path: sub, contents: contents},
+			redo:  true,
+			check: func(db *bolt.DB) bool { return buckets(db) == 0 },
+		},
+		{
+			name:      "redo delete bucket changed since",
+			edit:      edit{kind: editDeleteBucket, path: sub, contents: contents},
+			redo:      true,
+			meanwhile: put(sub, "x", "2"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, sub, "x") == "2" },
+		},
+		{
+			name:      "redo delete bucket added to since",
+			edit:      edit{kind: editDeleteBucket, path: sub, contents: contents},
+			redo:      true,
+			meanwhile: put(sub, "y", "2"),
+			wantErr:   bolt.ErrValueChanged,
+			check:     func(db *bolt.DB) bool { return value(db, sub, "y") == "2" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestModel(t, map[string]string{"k": "v"}).db
+			if tt.edit.kind == editDeleteBucket {
+				if err := db.WriteTree(sub, contents); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.edit.apply(db); err != nil {
+				t.Fatal(err)
+			}
+			if tt.redo {
+				if err := tt.edit.revert(db); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.meanwhile != nil {
+				if err := tt.meanwhile(db); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var err error
+			if tt.redo {
+				err = tt.edit.apply(db)
+			} else {
+				err = tt.edit.revert(db)
+			}
+			switch {
+			case tt.errAny && err == nil:
+				t.Error("expected an error")
+			case !tt.errAny && !errors.Is(err, tt.wantErr):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.check(db) {
+				t.Error("database is not in the expected state")
+			}
+		})
+	}
+}
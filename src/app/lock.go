@@ -0,0 +1,138 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// lockRetryInterval is the pause between attempts while waiting for a lock
+const lockRetryInterval = 1 * time.Second
+
+// openedMsg is sent when an attempt from the lock screen to open the
+// database is over
+type openedMsg struct {
+	db       *bolt.DB
+	snapshot *bolt.Snapshot
+	wait     bool // Whether the attempt was made while waiting for the lock
+	err      error
+}
+
+// showLockScreen explains why the database could not be opened and who
+// holds its lock
+func (m *Model) showLockScreen(err error) {
+	m.state = stateLocked
+	m.lockErr = err
+	m.lockHolders, _ = bolt.LockHolders(m.path)
+}
+
+// tryOpen attempts to open the database in the background
+func (m *Model) tryOpen(readOnly, snapshot, wait bool) tea.Cmd {
+	m.lockTrying = true
+	path, timeout := m.path, m.timeout
+	return func() tea.Msg {
+		db, snap, err := openDB(path, timeout, readOnly, snapshot)
+		return openedMsg{db, snap, wait, err}
+	}
+}
+
+// applyOpened leaves the lock screen once the database is open, or schedules
+// the next attempt while waiting for the lock
+func (m *Model) applyOpened(msg openedMsg) tea.Cmd {
+	m.lockTrying = false
+	if msg.err != nil {
+		m.showLockScreen(msg.err)
+		if msg.wait && m.lockWaiting && errors.Is(msg.err, bolt.ErrLocked) {
+			return tea.Tick(lockRetryInterval, func(time.Time) tea.Msg {
+				return retryOpenMsg{}
+			})
+		}
+		m.lockWaiting = false
+		return nil
+	}
+	m.lockWaiting = false
+	m.lockErr = nil
+	m.useDB(msg.db, msg.snapshot)
+	m.state = stateBuckets
+	return tea.Batch(m.loadBuckets, m.checkDB())
+}
+
+// retryOpenMsg triggers the next attempt while waiting for the lock
+type retryOpenMsg struct{}
+
+// updateLocked handles keys on the lock screen
+func (m *Model) updateLocked(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.lockWaiting = false
+		return m, nil
+	}
+	if m.lockTrying || m.lockWaiting {
+		return m, nil
+	}
+	switch msg.String() {
+	case "r":
+		return m, m.tryOpen(m.openReadOnly, false, false)
+	case "w":
+		m.lockWaiting = true
+		return m, m.tryOpen(m.openReadOnly, false, true)
+	case "o":
+		return m, m.tryOpen(true, false, false)
+	case "s":
+		return m, m.tryOpen(false, true, false)
+	}
+	return m, nil
+}
+
+// lockView renders the lock screen
+func (m *Model) lockView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Could not open the database: %v\n\n", m.lockErr))
+	if errors.Is(m.lockErr, bolt.ErrLocked) {
+		s.WriteString("BoltDB allows a single writer: another process has the file open and the lock\n")
+		s.WriteString(fmt.Sprintf("was not released within %v.\n\n", m.lockTimeout()))
+	}
+
+	if len(m.lockHolders) == 0 {
+		s.WriteString("The process holding the lock could not be found.\n\n")
+	} else {
+		s.WriteString("Held by:\n")
+		for _, h := range m.lockHolders {
+			mode := "read"
+			if h.Write {
+				mode = "write"
+			}
+			command := h.Command
+			if command == "" {
+				command = "unknown command"
+			}
+			s.WriteString(fmt.Sprintf("  PID %d (%s lock): %s\n", h.PID, mode, command))
+		}
+		s.WriteString("\n")
+	}
+
+	switch {
+	case m.lockWaiting:
+		s.WriteString("Waiting for the lock to be released… (esc to stop)")
+	case m.lockTrying:
+		s.WriteString("Opening…")
+	default:
+		s.WriteString("r: retry · w: wait for the lock · o: open read-only · s: open a snapshot · q: quit\n\n")
+		s.WriteString(m.styles.Help.Render("Read-only access is possible while the holders only read; a snapshot always works."))
+	}
+	return s.String()
+}
+
+// lockTimeout returns how long opening waits for the lock
+func (m *Model) lockTimeout() time.Duration {
+	if m.timeout == 0 {
+		return 1 * time.Second
+	}
+	return m.timeout
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+func TestRetryKeepsReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	holder := &bolt.DB{Path: path}
+	if err := holder.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := New(path, Options{ReadOnly: true, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	if m.state != stateLocked {
+		t.Fatalf("state = %v, want the lock screen", m.state)
+	}
+	holder.Close()
+
+	for _, k := range []string{"r", "w"} {
+		m.lockTrying, m.lockWaiting = false, false
+		_, cmd := m.updateLocked(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		msg := cmd().(openedMsg)
+		if msg.err != nil {
+			t.Fatal(msg.err)
+		}
+		if !msg.db.ReadOnly {
+			t.Errorf("%s reopened the database read-write", k)
+		}
+		msg.db.Close()
+	}
+}
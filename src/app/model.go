@@ -1,16 +1,23 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
 )
 
 type state int
@@ -24,6 +31,17 @@ const (
 	stateEditKey
 	stateConfirmDelete
 	stateConfirmDeleteBucket
+	stateFilter
+	stateSearch
+	stateViewValue
+	stateConfirmEdit
+	stateLocked
+	stateStats
+	stateCompact
+	stateCompacting
+	stateExport
+	stateHistory
+	stateCommit
 )
 
 // KeyMap defines keybindings
@@ -33,6 +51,7 @@ type KeyMap struct {
 	Left         key.Binding
 	Right        key.Binding
 	NewTab       key.Binding
+	NewBucket    key.Binding
 	New          key.Binding
 	Delete       key.Binding
 	DeleteBucket key.Binding
@@ -45,6 +64,24 @@ type KeyMap struct {
 	SelectTab    key.Binding
 	Edit         key.Binding
 	EditBucket   key.Binding
+	Filter       key.Binding
+	Search       key.Binding
+	SearchMode   key.Binding
+	View         key.Binding
+	HexView      key.Binding
+	Save         key.Binding
+	External     key.Binding
+	Codec        key.Binding
+	Refresh      key.Binding
+	Stats        key.Binding
+	Compact      key.Binding
+	Export       key.Binding
+	Undo         key.Binding
+	Redo         key.Binding
+	History      key.Binding
+	Stage        key.Binding
+	Commit       key.Binding
+	Discard      key.Binding
 }
 
 // DefaultKeyMap returns default keybindings
@@ -70,6 +107,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+t"),
 			key.WithHelp("ctrl+t", "new tab"),
 		),
+		NewBucket: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "new sub-bucket"),
+		),
 		New: key.NewBinding(
 			key.WithKeys("ctrl+n"),
 			key.WithHelp("ctrl+n", "new key"),
@@ -79,16 +120,16 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("ctrl+d", "delete"),
 		),
 		DeleteBucket: key.NewBinding(
-			key.WithKeys("ctrl+r"),
-			key.WithHelp("ctrl+r", "remove bucket"),
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "remove bucket"),
 		),
 		Enter: key.NewBinding(
 			key.WithKeys("enter"),
-			key.WithHelp("enter", "select/confirm"),
+			key.WithHelp("enter", "select/open bucket"),
 		),
 		Esc: key.NewBinding(
 			key.WithKeys("esc"),
-			key.WithHelp("esc", "back"),
+			key.WithHelp("esc", "back/parent bucket"),
 		),
 		Quit: key.NewBinding(
 			key.WithKeys("ctrl+c"),
@@ -119,6 +160,86 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+e"),
 			key.WithHelp("ctrl+e", "edit bucket/key"),
 		),
+		Filter: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "filter by prefix/range"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search all buckets"),
+		),
+		SearchMode: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch search mode"),
+		),
+		View: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "view value"),
+		),
+		HexView: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "toggle hex dump"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "review and save value"),
+		),
+		External: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit value in $EDITOR"),
+		),
+		Codec: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "switch value codec"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "refresh snapshot"),
+		),
+		Stats: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "database stats"),
+		),
+		Compact: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", "compact into a new file"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "export bucket"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "edit history"),
+		),
+		Stage: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "toggle staging mode"),
+		),
+		Commit: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "review staged changes"),
+		),
+		Discard: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "discard staged changes"),
+		),
+	}
+}
+
+// disableWrites turns off the bindings of actions that write to the database
+func (k *KeyMap) disableWrites() {
+	for _, b := range []*key.Binding{&k.NewTab, &k.NewBucket, &k.New, &k.Delete, &k.DeleteBucket,
+		&k.Edit, &k.EditBucket, &k.Save, &k.External, &k.Undo, &k.Redo, &k.Stage, &k.Commit, &k.Discard} {
+		b.SetEnabled(false)
 	}
 }
 
@@ -133,6 +254,14 @@ type Styles struct {
 	Selected       lipgloss.Style
 	Help           lipgloss.Style
 	Title          lipgloss.Style
+	Badge          lipgloss.Style
+	Breadcrumb     lipgloss.Style
+	DiffInsert     lipgloss.Style
+	DiffDelete     lipgloss.Style
+	JSONKey        lipgloss.Style
+	JSONString     lipgloss.Style
+	JSONNumber     lipgloss.Style
+	JSONLiteral    lipgloss.Style
 }
 
 // DefaultStyles returns default styles
@@ -146,6 +275,14 @@ func DefaultStyles() Styles {
 	selected := lipgloss.NewStyle().Padding(0, 1).Background(lipgloss.Color("#1E88E5")).Foreground(lipgloss.Color("#FFFFFF"))
 	help := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
 	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#3949AB")).Padding(1, 2)
+	badge := lipgloss.NewStyle().Bold(true).Padding(0, 1).Background(lipgloss.Color("#E53935")).Foreground(lipgloss.Color("#FFFFFF"))
+	breadcrumb := lipgloss.NewStyle().Foreground(lipgloss.Color("#BBBBBB"))
+	diffInsert := lipgloss.NewStyle().Foreground(lipgloss.Color("#43A047"))
+	diffDelete := lipgloss.NewStyle().Foreground(lipgloss.Color("#E53935"))
+	jsonKey := lipgloss.NewStyle().Foreground(lipgloss.Color("#1E88E5"))
+	jsonString := lipgloss.NewStyle().Foreground(lipgloss.Color("#43A047"))
+	jsonNumber := lipgloss.NewStyle().Foreground(lipgloss.Color("#FB8C00"))
+	jsonLiteral := lipgloss.NewStyle().Foreground(lipgloss.Color("#8E24AA"))
 
 	return Styles{
 		Tab:            tab,
@@ -157,18 +294,89 @@ func DefaultStyles() Styles {
 		Selected:       selected,
 		Help:           help,
 		Title:          title,
+		Badge:          badge,
+		Breadcrumb:     breadcrumb,
+		DiffInsert:     diffInsert,
+		DiffDelete:     diffDelete,
+		JSONKey:        jsonKey,
+		JSONString:     jsonString,
+		JSONNumber:     jsonNumber,
+		JSONLiteral:    jsonLiteral,
 	}
 }
 
+// entry describes what a row of the key table points at. The raw key is kept
+// so that binary keys can be addressed exactly, display is what the table shows.
+type entry struct {
+	key      []byte
+	display  string
+	value    string // Display form of the start of the value
+	isBucket bool
+	mark     string // Marker of a staged change, empty when the key is unchanged
+}
+
 type Model struct {
 	db                 *bolt.DB
+	path               string        // Path of the database as given, also when browsing a snapshot
+	timeout            time.Duration // How long to wait for the lock of the database
+	openReadOnly       bool          // Opened with Options.ReadOnly, kept when retrying from the lock screen
+	lockErr            error         // Why the database could not be opened, on the lock screen
+	lockHolders        []bolt.LockHolder
+	lockWaiting        bool // Waiting on the lock screen for the lock to be released
+	lockTrying         bool // An attempt to open the database is in flight
+	statsLoading       bool // Statistics are being gathered for the stats view
+	compacting         bool // A compaction is running
+	compactDst         string
+	compactItems       int   // Keys and buckets copied so far
+	compactBytes       int64 // Bytes of keys and values copied so far
+	compactResult      *bolt.CompactResult
+	checkReport        *bolt.CheckReport // Result of the integrity check, nil until it is done
+	exporting          bool              // An export is running
+	exportCount        int               // Records exported, -1 until the export is done
+	exportFile         string
+	readOnly           bool
+	snapshot           *bolt.Snapshot // Copy being browsed instead of the database, if any
+	snapshotting       bool           // A snapshot refresh is in flight
 	state              state
 	buckets            []string
 	activeTab          int
 	table              table.Model
-	currentBucket      string
-	currentKey         string
+	bucketPath         []string // Path of the bucket shown in the table, top-level first
+	entries            []entry  // Loaded window of rows of the table, in the same order
+	hasPrev            bool     // Entries exist before the loaded window
+	hasNext            bool     // Entries exist after the loaded window
+	loading            bool     // A page load is in flight
+	pageSeq            int      // Incremented for each page load so stale results can be dropped
+	filter             bolt.Range
+	filterText         string                 // Filter as typed by the user, empty when not filtering
+	codecs             map[string]codec.Codec // Codec chosen per bucket path, detected when missing
+	codecList          []codec.Codec          // Codecs cycled through when switching
+	wireFallback       bool                   // Options.WireFallback
+	searchInput        textinput.Model
+	searchTable        table.Model
+	searchMode         searchMode
+	searchRanMode      searchMode // Mode of the search that produced the results
+	searchQuery        string     // Query of the search that produced the results
+	searchResults      []searchResult
+	searchID           int // Incremented for each search so stale results can be dropped
+	searching          bool
+	searchErr          error
+	searchCancel       context.CancelFunc
+	searchDone         chan struct{} // Closed once the background walk has returned
+	viewer             viewport.Model
+	viewKey            []byte // Key whose value is shown in the viewer
+	viewValue          []byte
+	viewKind           string // How the viewed value is rendered, e.g. JSON or text
+	viewHex            bool   // Whether the viewed value is shown as a hex dump
+	viewDecoded        string // JSON form of the viewed value when a codec decoded it
+	currentKey         []byte
 	value              string
+	editor             textarea.Model
+	editOriginal       []byte      // Value of the key when editing started
+	editValueNew       []byte      // Edited value waiting for confirmation
+	valueEscaped       bool        // Whether the value being edited is shown in its escaped form
+	editCodec          codec.Codec // Codec of the value being edited, nil when edited as stored
+	editContent        string      // Editor content when editing started
 	textInput          textinput.Model
 	help               help.Model
 	keyMap             KeyMap
@@ -177,20 +385,46 @@ type Model struct {
 	height             int
 	showHelp           bool
 	err                error
-	originalBucketName string // For storing original bucket name during editing
-	originalKeyName    string // For storing original key name during editing
-	deleteKey          string // For storing key name to be deleted
-	deleteBucket       string // For storing bucket name to be deleted
-	newlyCreatedBucket string // For tracking newly created bucket to make it active
+	parentPath         []string // For storing the parent of a bucket being created
+	originalBucketPath []string // For storing original bucket path during editing
+	originalKeyName    []byte   // For storing original key name during editing
+	deleteKey          []byte   // For storing key name to be deleted
+	deleteBucketPath   []string // For storing bucket path to be deleted
+	newlyCreatedBucket string   // For tracking newly created bucket to make it active
+	undoStack          []edit   // Edits made, the last one first to undo
+	redoStack          []edit   // Edits undone, the last one first to redo
+	staging            bool     // Edits are staged instead of written
+	staged             []edit   // Staged edits, in the order they were made
+	quitStaged         bool     // Quitting was asked once with staged edits
 }
 
-func New(dbPath string) (*Model, error) {
-	db := &bolt.DB{Path: dbPath}
-	err := db.Open()
-	if err != nil {
-		return nil, err
-	}
+// Options configures the application
+type Options struct {
+	// ReadOnly opens the database with a shared lock and disables every
+	// action that writes to it
+	ReadOnly bool
+	// Snapshot browses a copy of the database, for when it is locked by
+	// another process. Snapshots are read-only.
+	Snapshot bool
+	// Timeout is how long to wait for the lock of the database before
+	// showing the lock screen, 1 second when zero
+	Timeout time.Duration
+	// Staging starts in staging mode, where edits are kept in memory until
+	// they are reviewed and committed together
+	Staging bool
+
+	// Codecs maps bucket paths, with their parts joined by "/", to the codec
+	// of their values, instead of detecting the codec of each value
+	Codecs map[string]codec.Codec
+	// WireFallback shows binary values of buckets without a codec as
+	// protobuf wire format when they parse as one, for databases of
+	// protobuf messages
+	WireFallback bool
+}
 
+// New opens the database at dbPath. When another process holds its lock the
+// model starts on the lock screen instead of failing.
+func New(dbPath string, opts Options) (*Model, error) {
 	// Initialize table
 	columns := []table.Column{
 		{Title: "Key", Width: 30},
@@ -218,30 +452,118 @@ func New(dbPath string) (*Model, error) {
 	ti.Placeholder = "Enter name..."
 	ti.Focus()
 
+	// Initialize the multi-line value editor
+	ta := textarea.New()
+	ta.ShowLineNumbers = true
+	ta.MaxHeight = 0
+	ta.MaxWidth = 0
+
+	// Initialize global search
+	si := textinput.New()
+	si.Placeholder = "Search keys and values..."
+	st := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Bucket", Width: 20},
+			{Title: "Key", Width: 25},
+			{Title: "In", Width: 6},
+			{Title: "Match", Width: 40},
+		}),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	st.SetStyles(table.Styles{
+		Header:   s.Header,
+		Cell:     s.Cell,
+		Selected: s.Selected,
+	})
+
 	// Initialize help
-	km := DefaultKeyMap()
 	h := help.New()
 
 	m := &Model{
-		db:        db,
-		state:     stateBuckets,
-		table:     tbl,
-		textInput: ti,
-		help:      h,
-		keyMap:    km,
-		styles:    s,
-		showHelp:  false,
+		path:         dbPath,
+		timeout:      opts.Timeout,
+		openReadOnly: opts.ReadOnly,
+		staging:      opts.Staging,
+		state:        stateBuckets,
+		table:        tbl,
+		textInput:    ti,
+		searchInput:  si,
+		searchTable:  st,
+		viewer:       viewport.New(80, 20),
+		editor:       ta,
+		codecs:       make(map[string]codec.Codec),
+		codecList:    codecList(opts.Codecs),
+		wireFallback: opts.WireFallback,
+		help:         h,
+		keyMap:       DefaultKeyMap(),
+		styles:       s,
+		showHelp:     false,
+	}
+	for path, c := range opts.Codecs {
+		m.codecs[pathKey(strings.Split(path, "/"))] = c
 	}
 
+	db, snapshot, err := openDB(dbPath, opts.Timeout, opts.ReadOnly, opts.Snapshot)
+	if errors.Is(err, bolt.ErrLocked) {
+		m.showLockScreen(err)
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.useDB(db, snapshot)
+
 	return m, nil
 }
 
+// openDB opens the database at path, or a snapshot of it. Snapshots are
+// always opened read-only.
+func openDB(path string, timeout time.Duration, readOnly, snapshot bool) (*bolt.DB, *bolt.Snapshot, error) {
+	var snap *bolt.Snapshot
+	if snapshot {
+		var err error
+		snap, err = bolt.TakeSnapshot(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = snap.Path
+		readOnly = true
+	}
+
+	db := &bolt.DB{Path: path, ReadOnly: readOnly, Timeout: timeout}
+	if err := db.Open(); err != nil {
+		if snap != nil {
+			snap.Remove()
+		}
+		return nil, nil, err
+	}
+	return db, snap, nil
+}
+
+// useDB switches the model to an opened database, enabling the actions it
+// allows
+func (m *Model) useDB(db *bolt.DB, snapshot *bolt.Snapshot) {
+	m.db = db
+	m.snapshot = snapshot
+	m.readOnly = db.ReadOnly
+	m.keyMap = DefaultKeyMap()
+	if m.readOnly {
+		m.keyMap.disableWrites()
+		m.staging = false
+	}
+	m.keyMap.Refresh.SetEnabled(snapshot != nil)
+}
+
 func (m *Model) Init() tea.Cmd {
-	return m.loadBuckets
+	if m.state == stateLocked {
+		return nil
+	}
+	return tea.Batch(m.loadBuckets, m.checkDB())
 }
 
 func (m *Model) loadBuckets() tea.Msg {
-	buckets, err := m.db.GetBuckets()
+	buckets, err := m.db.GetBuckets(nil)
 	if err != nil {
 		return err
 	}
@@ -253,61 +575,388 @@ type bucketsLoadedMsg struct {
 	buckets []string
 }
 
-func (m *Model) loadKeysAndValues(bucket string) tea.Cmd {
+const (
+	pageSize   = 100          // Entries fetched per page
+	windowSize = 5 * pageSize // Entries kept in memory around the cursor
+	pageMargin = pageSize / 4 // Distance from the window edge that triggers a load
+	previewLen = 256          // Bytes of a value rendered in the table
+)
+
+type pageMode int
+
+const (
+	pageReplace pageMode = iota // Replace the window, e.g. when opening a bucket
+	pageRefresh                 // Reload the current window in place after an edit
+	pageAppend                  // Extend the window forward while scrolling down
+	pagePrepend                 // Extend the window backward while scrolling up
+	pageLast                    // Replace the window with the last page
+)
+
+// loadKeysAndValues loads the first page of the bucket at path
+func (m *Model) loadKeysAndValues(path []string) tea.Cmd {
+	m.loading = true
+	return m.loadPage(path, nil, pageSize, pageReplace)
+}
+
+// typing reports whether keys are going to a text input or editor
+func (m *Model) typing() bool {
+	switch m.state {
+	case stateCreateBucket, stateCreateKey, stateEditBucket, stateEditKey, stateEditValue, stateFilter, stateSearch, stateCompact:
+		return true
+	case stateExport:
+		return !m.exporting && m.exportCount < 0
+	}
+	return false
+}
+
+// openBucket shows the bucket at path from its first key, dropping any filter
+func (m *Model) openBucket(path []string) tea.Cmd {
+	return m.openBucketAt(path, nil)
+}
+
+// openBucketAt shows the bucket at path starting at key at
+func (m *Model) openBucketAt(path []string, at []byte) tea.Cmd {
+	m.bucketPath = path
+	m.filter = bolt.Range{}
+	m.filterText = ""
+	m.loading = true
+	return m.loadPage(path, at, pageSize, pageReplace)
+}
+
+// reloadKeys reloads the current window from its first key so the cursor
+// stays where it was after an edit
+func (m *Model) reloadKeys() tea.Cmd {
+	if len(m.entries) == 0 {
+		return m.loadKeysAndValues(m.bucketPath)
+	}
+	m.loading = true
+	return m.loadPage(m.bucketPath, m.entries[0].key, max(len(m.entries), pageSize), pageRefresh)
+}
+
+func (m *Model) loadPage(path []string, from []byte, limit int, mode pageMode) tea.Cmd {
+	m.pageSeq++
+	seq := m.pageSeq
+	db, filter := m.db, m.filter
+	chosen, wire := m.codecs[pathKey(path)], m.wireFallback
+	var staged map[string]stagedKey
+	if len(m.staged) > 0 {
+		staged = m.stagedKeys(path)
+	}
 	return func() tea.Msg {
-		keys, err := m.db.GetKeysInBucket(bucket)
+		var (
+			page bolt.Page
+			err  error
+		)
+		switch mode {
+		case pagePrepend, pageLast:
+			page, err = db.ScanBackward(path, filter, from, limit)
+		default:
+			page, err = db.Scan(path, filter, from, limit)
+		}
 		if err != nil {
 			return err
 		}
+		if len(staged) > 0 {
+			page = stagePage(page, staged, filter, from, limit, mode == pagePrepend || mode == pageLast)
+		}
 
-		rows := make([]table.Row, len(keys))
-		for i, k := range keys {
-			value, err := m.db.GetValue(bucket, k)
-			if err != nil {
-				rows[i] = table.Row{k, "Error: " + err.Error()}
-			} else {
-				rows[i] = table.Row{k, string(value)}
+		entries := make([]entry, len(page.Entries))
+		for i, e := range page.Entries {
+			entries[i] = newTableEntry(e, chosen, wire)
+			if s, ok := staged[string(e.Key)]; ok && !e.IsBucket {
+				entries[i].mark = s.mark()
 			}
 		}
+		return pageLoadedMsg{seq, mode, entries, page.HasPrev, page.HasNext}
+	}
+}
 
-		return keysLoadedMsg{rows}
+// newTableEntry converts a bucket entry into a table entry with its display
+// forms. Values a codec can decode are previewed in their decoded form.
+func newTableEntry(e bolt.Entry, chosen codec.Codec, wire bool) entry {
+	te := entry{key: e.Key, display: displayBytes(e.Key), isBucket: e.IsBucket}
+	if e.IsBucket {
+		te.value = "<bucket>"
+		return te
+	}
+	if len(e.Value) <= previewDecodeLimit {
+		if c, tree, err := decodeWith(chosen, e.Value, wire); c != nil && err == nil {
+			te.value = codec.Format(tree, "")
+			if runes := []rune(te.value); len(runes) > previewLen {
+				te.value = string(runes[:previewLen]) + "…"
+			}
+			return te
+		}
+	}
+	if len(e.Value) > previewLen {
+		te.value = displayBytes(e.Value[:previewLen]) + "…"
+	} else {
+		te.value = displayBytes(e.Value)
 	}
+	return te
 }
 
-type keysLoadedMsg struct {
-	rows []table.Row
+type pageLoadedMsg struct {
+	seq     int
+	mode    pageMode
+	entries []entry
+	hasPrev bool
+	hasNext bool
+}
+
+// applyPage merges a loaded page into the window of entries, trimming the
+// far side of the window so memory stays bounded however big the bucket is
+func (m *Model) applyPage(msg pageLoadedMsg) {
+	cursor := m.table.Cursor()
+	switch msg.mode {
+	case pageReplace:
+		m.entries, m.hasPrev, m.hasNext = msg.entries, msg.hasPrev, msg.hasNext
+		cursor = 0
+	case pageRefresh:
+		m.entries, m.hasPrev, m.hasNext = msg.entries, msg.hasPrev, msg.hasNext
+	case pageLast:
+		m.entries, m.hasPrev, m.hasNext = msg.entries, msg.hasPrev, msg.hasNext
+		cursor = len(m.entries) - 1
+	case pageAppend:
+		m.entries = append(m.entries, msg.entries...)
+		m.hasNext = msg.hasNext
+		if drop := len(m.entries) - windowSize; drop > 0 {
+			m.entries = m.entries[drop:]
+			m.hasPrev = true
+			cursor -= drop
+		}
+	case pagePrepend:
+		m.entries = append(msg.entries, m.entries...)
+		m.hasPrev = msg.hasPrev
+		cursor += len(msg.entries)
+		if len(m.entries) > windowSize {
+			m.entries = m.entries[:windowSize]
+			m.hasNext = true
+		}
+	}
+
+	rows := make([]table.Row, len(m.entries))
+	for i, e := range m.entries {
+		if e.isBucket {
+			rows[i] = table.Row{"▸ " + e.display, e.value}
+		} else if e.mark != "" {
+			rows[i] = table.Row{e.mark + " " + e.display, e.value}
+		} else {
+			rows[i] = table.Row{e.display, e.value}
+		}
+	}
+	m.table.SetRows(rows)
+	m.table.SetCursor(min(max(cursor, 0), max(len(rows)-1, 0)))
+}
+
+// loadMore fetches the next or previous page when the cursor gets close to
+// the edge of the loaded window
+func (m *Model) loadMore() tea.Cmd {
+	if m.loading || len(m.entries) == 0 {
+		return nil
+	}
+	cursor := m.table.Cursor()
+	if m.hasNext && cursor >= len(m.entries)-pageMargin {
+		m.loading = true
+		return m.loadPage(m.bucketPath, bolt.After(m.entries[len(m.entries)-1].key), pageSize, pageAppend)
+	}
+	if m.hasPrev && cursor < pageMargin {
+		m.loading = true
+		return m.loadPage(m.bucketPath, m.entries[0].key, pageSize, pagePrepend)
+	}
+	return nil
+}
+
+// selectedEntry returns the entry under the table cursor
+func (m *Model) selectedEntry() (entry, bool) {
+	i := m.table.Cursor()
+	if i < 0 || i >= len(m.entries) {
+		return entry{}, false
+	}
+	return m.entries[i], true
+}
+
+// childPath returns the path of a bucket nested in the current bucket
+func (m *Model) childPath(name []byte) []string {
+	path := make([]string, 0, len(m.bucketPath)+1)
+	path = append(path, m.bucketPath...)
+	return append(path, string(name))
+}
+
+// reloadAfterBucketChange refreshes the tabs when a top-level bucket changed,
+// or only the table when the change happened inside a nested bucket
+func (m *Model) reloadAfterBucketChange(path []string) tea.Cmd {
+	if len(path) <= 1 {
+		return m.loadBuckets
+	}
+	return m.reloadKeys()
+}
+
+// quit closes the database and exits. Staged changes are only dropped when
+// quitting is asked twice.
+func (m *Model) quit() tea.Cmd {
+	if len(m.staged) > 0 && !m.quitStaged {
+		m.quitStaged = true
+		m.err = fmt.Errorf("%d staged changes are not committed, press ctrl+c again to quit without them", len(m.staged))
+		return nil
+	}
+	m.cancelSearch()
+	m.Close()
+	return tea.Quit
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.state == stateSearch {
+		return m.updateSearch(msg)
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok && m.state == stateLocked {
+		return m.updateLocked(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keyMap.Quit):
-			m.db.Close()
-			return m, tea.Quit
+			return m, m.quit()
 
 		case key.Matches(msg, m.keyMap.Help):
-			m.showHelp = !m.showHelp
-			return m, nil
+			// Let "?" be typed in text inputs
+			if !m.typing() {
+				m.showHelp = !m.showHelp
+				return m, nil
+			}
 
 		case key.Matches(msg, m.keyMap.Esc):
-			if m.state == stateCreateBucket || m.state == stateCreateKey ||
+			if m.state == stateConfirmEdit {
+				// Back to the editor to keep working on the value
+				m.state = stateEditValue
+				return m, m.editor.Focus()
+			} else if m.state == stateCreateBucket || m.state == stateCreateKey ||
 				m.state == stateEditBucket || m.state == stateEditKey || m.state == stateEditValue ||
-				m.state == stateConfirmDelete || m.state == stateConfirmDeleteBucket {
+				m.state == stateConfirmDelete || m.state == stateConfirmDeleteBucket ||
+				m.state == stateFilter || m.state == stateViewValue || m.state == stateStats ||
+				m.state == stateCompact || (m.state == stateCompacting && !m.compacting) || m.state == stateHistory || m.state == stateCommit ||
+				(m.state == stateExport && !m.exporting) {
 				m.state = stateBuckets
 				return m, nil
+			} else if m.state == stateBuckets && m.filterText != "" {
+				// Clear the filter before leaving the bucket
+				return m, m.openBucket(m.bucketPath)
+			} else if m.state == stateBuckets && len(m.bucketPath) > 1 {
+				// Climb back to the parent bucket
+				return m, m.openBucket(m.bucketPath[:len(m.bucketPath)-1])
+			}
+		case key.Matches(msg, m.keyMap.View):
+			if m.state == stateBuckets && len(m.table.Rows()) > 0 {
+				if selected, ok := m.selectedEntry(); ok && !selected.isBucket {
+					return m, m.openViewer(selected.key)
+				}
+			}
+		case key.Matches(msg, m.keyMap.External):
+			if m.state == stateViewValue {
+				return m, m.openExternalEditor(m.viewKey)
+			}
+			if m.state == stateBuckets && len(m.table.Rows()) > 0 {
+				if selected, ok := m.selectedEntry(); ok && !selected.isBucket {
+					return m, m.openExternalEditor(selected.key)
+				}
+			}
+		case key.Matches(msg, m.keyMap.Stats):
+			if m.state == stateBuckets {
+				return m, m.openStats()
+			}
+		case key.Matches(msg, m.keyMap.Compact):
+			if m.state == stateBuckets {
+				return m, m.openCompact()
+			}
+		case key.Matches(msg, m.keyMap.Export):
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
+				return m, m.openExport()
+			}
+		case key.Matches(msg, m.keyMap.Undo):
+			if m.state == stateBuckets || m.state == stateHistory {
+				// Undo drops staged changes while staging
+				if m.staging {
+					return m, m.unstage()
+				}
+				return m, m.undo()
+			}
+		case key.Matches(msg, m.keyMap.Redo):
+			if m.state == stateBuckets || m.state == stateHistory {
+				if m.staging {
+					m.err = fmt.Errorf("redo is not available in staging mode")
+					return m, nil
+				}
+				return m, m.redo()
+			}
+		case key.Matches(msg, m.keyMap.Stage):
+			if m.state == stateBuckets {
+				return m, m.toggleStaging()
+			}
+		case key.Matches(msg, m.keyMap.Commit):
+			if m.state == stateBuckets && m.staging {
+				return m, m.openCommit()
+			}
+		case key.Matches(msg, m.keyMap.Discard):
+			if m.state == stateCommit {
+				return m, m.discardStaged()
+			}
+		case key.Matches(msg, m.keyMap.History):
+			if m.state == stateBuckets && !m.readOnly {
+				return m, m.openHistory()
+			}
+		case key.Matches(msg, m.keyMap.Refresh):
+			if m.state == stateBuckets {
+				return m, m.refreshSnapshot()
+			}
+		case key.Matches(msg, m.keyMap.Codec):
+			if m.state == stateViewValue {
+				m.cycleCodec()
+				return m, tea.Batch(m.openViewer(m.viewKey), m.reloadKeys())
+			}
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
+				m.cycleCodec()
+				return m, m.reloadKeys()
+			}
+		case key.Matches(msg, m.keyMap.HexView):
+			if m.state == stateViewValue {
+				m.toggleHex()
+				return m, nil
+			}
+		case key.Matches(msg, m.keyMap.Search):
+			if m.state == stateBuckets && len(m.buckets) > 0 {
+				return m, m.openSearch()
+			}
+		case key.Matches(msg, m.keyMap.Filter):
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
+				m.state = stateFilter
+				m.textInput.SetValue(m.filterText)
+				m.textInput.CursorEnd()
+				return m, textinput.Blink
 			}
 		case key.Matches(msg, m.keyMap.NewTab):
 			if m.state == stateBuckets {
 				m.state = stateCreateBucket
+				m.parentPath = nil
 				m.textInput.SetValue("")
 				return m, textinput.Blink
 			} else if m.state == stateCreateBucket {
 				m.state = stateBuckets
 				return m, nil
 			}
+		case key.Matches(msg, m.keyMap.NewBucket):
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
+				m.state = stateCreateBucket
+				m.parentPath = m.bucketPath
+				m.textInput.SetValue("")
+				return m, textinput.Blink
+			}
+		case key.Matches(msg, m.keyMap.Save):
+			if m.state == stateEditValue {
+				return m, m.reviewEdit()
+			}
 		case key.Matches(msg, m.keyMap.New):
-			if len(m.buckets) > 0 {
+			if m.state == stateBuckets && len(m.buckets) > 0 {
 				m.state = stateCreateKey
 				m.textInput.SetValue("")
 				return m, textinput.Blink
@@ -315,167 +964,200 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, m.keyMap.Edit):
 			if m.state == stateBuckets && len(m.buckets) > 0 && len(m.table.Rows()) > 0 {
-				selectedRow := m.table.SelectedRow()
-				if selectedRow != nil {
+				if selected, ok := m.selectedEntry(); ok {
+					if selected.isBucket {
+						// Edit the selected nested bucket name
+						m.originalBucketPath = m.childPath(selected.key)
+						m.textInput.SetValue(selected.display)
+						m.state = stateEditBucket
+						return m, textinput.Blink
+					}
 					// Edit the selected key
-					m.originalKeyName = selectedRow[0]
+					m.originalKeyName = selected.key
 					m.currentKey = m.originalKeyName
-					m.textInput.SetValue(m.originalKeyName)
+					m.textInput.SetValue(selected.display)
 					m.state = stateEditKey
 					return m, textinput.Blink
 				}
 			}
 
 		case key.Matches(msg, m.keyMap.EditBucket):
-			if m.state == stateBuckets && len(m.buckets) > 0 {
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
 				// Edit the current bucket name
-				m.originalBucketName = m.currentBucket
-				m.textInput.SetValue(m.currentBucket)
+				m.originalBucketPath = m.bucketPath
+				m.textInput.SetValue(displayBytes([]byte(m.bucketPath[len(m.bucketPath)-1])))
 				m.state = stateEditBucket
 				return m, textinput.Blink
 			}
 
 		case key.Matches(msg, m.keyMap.Delete):
 			if m.state == stateBuckets && len(m.buckets) > 0 && len(m.table.Rows()) > 0 {
-				selectedRow := m.table.SelectedRow()
-				if selectedRow != nil {
+				if selected, ok := m.selectedEntry(); ok {
+					if selected.isBucket {
+						// Deleting a nested bucket row removes the whole bucket
+						m.deleteBucketPath = m.childPath(selected.key)
+						m.state = stateConfirmDeleteBucket
+						return m, nil
+					}
 					// Store the key to be deleted and show confirmation
-					m.deleteKey = selectedRow[0]
+					m.deleteKey = selected.key
 					m.state = stateConfirmDelete
 					return m, nil
 				}
 			}
 
 		case key.Matches(msg, m.keyMap.DeleteBucket):
-			if m.state == stateBuckets && len(m.buckets) > 0 {
+			if m.state == stateBuckets && len(m.bucketPath) > 0 {
 				// Store the bucket to be deleted and show confirmation
-				m.deleteBucket = m.currentBucket
+				m.deleteBucketPath = m.bucketPath
 				m.state = stateConfirmDeleteBucket
 				return m, nil
 			}
 
 		case key.Matches(msg, m.keyMap.PrevTab):
-			if m.state == stateBuckets && len(m.buckets) > 0 {
+			// Tabs only switch between top-level buckets
+			if m.state == stateBuckets && len(m.buckets) > 0 && len(m.bucketPath) <= 1 {
 				m.activeTab = max(0, m.activeTab-1)
-				m.currentBucket = m.buckets[m.activeTab]
-				return m, m.loadKeysAndValues(m.currentBucket)
+				return m, m.openBucket([]string{m.buckets[m.activeTab]})
 			}
 
 		case key.Matches(msg, m.keyMap.NextTab):
-			if m.state == stateBuckets && len(m.buckets) > 0 {
+			if m.state == stateBuckets && len(m.buckets) > 0 && len(m.bucketPath) <= 1 {
 				m.activeTab = min(len(m.buckets)-1, m.activeTab+1)
-				m.currentBucket = m.buckets[m.activeTab]
-				return m, m.loadKeysAndValues(m.currentBucket)
+				return m, m.openBucket([]string{m.buckets[m.activeTab]})
 			}
 
 		case key.Matches(msg, m.keyMap.Enter):
-			if m.state == stateConfirmDelete {
+			if m.state == stateViewValue {
+				if m.readOnly {
+					return m, nil
+				}
+				return m, m.editValue(m.viewKey)
+			} else if m.state == stateFilter {
+				// Apply the filter, or clear it when the input is empty
+				m.filterText = m.textInput.Value()
+				m.filter = parseFilter(m.filterText)
+				m.state = stateBuckets
+				return m, m.loadKeysAndValues(m.bucketPath)
+			} else if m.state == stateConfirmDelete {
 				// Delete the key
-				err := m.db.DeleteValue(m.currentBucket, m.deleteKey)
+				err := m.deleteValue(m.bucketPath, m.deleteKey)
 				if err != nil {
 					m.err = err
 					return m, nil
 				}
 				m.state = stateBuckets
-				m.deleteKey = ""
-				return m, m.loadKeysAndValues(m.currentBucket)
+				m.deleteKey = nil
+				return m, m.reloadKeys()
 			} else if m.state == stateConfirmDeleteBucket {
 				// Delete the bucket
-				err := m.db.DeleteBucket(m.deleteBucket)
+				path := m.deleteBucketPath
+				err := m.deleteBucket(path)
 				if err != nil {
 					m.err = err
 					return m, nil
 				}
 				m.state = stateBuckets
-				m.deleteBucket = ""
-				// Reset activeTab if we deleted the current bucket
-				if m.activeTab >= len(m.buckets)-1 {
-					m.activeTab = max(0, len(m.buckets)-2)
+				m.deleteBucketPath = nil
+				if len(path) == 1 {
+					// Reset activeTab if we deleted the current bucket
+					if m.activeTab >= len(m.buckets)-1 {
+						m.activeTab = max(0, len(m.buckets)-2)
+					}
+					m.bucketPath = nil
+					return m, m.loadBuckets
+				}
+				// Climb out of the deleted bucket if we were inside it
+				if len(m.bucketPath) >= len(path) {
+					return m, m.openBucket(path[:len(path)-1])
 				}
-				return m, m.loadBuckets
+				return m, m.reloadKeys()
 			} else if m.state == stateCreateBucket {
-				newBucket := m.textInput.Value()
+				newBucket := string(parseBytes(m.textInput.Value()))
 				if newBucket != "" {
-					err := m.db.CreateBucket(newBucket)
+					path := append(append([]string{}, m.parentPath...), newBucket)
+					err := m.createBucket(path)
 					if err != nil {
 						m.err = err
 						return m, nil
 					}
-					m.newlyCreatedBucket = newBucket
 					m.state = stateBuckets
-					return m, m.loadBuckets
+					if len(path) == 1 {
+						m.newlyCreatedBucket = newBucket
+						m.bucketPath = nil
+					}
+					return m, m.reloadAfterBucketChange(path)
 				}
 			} else if m.state == stateCreateKey && len(m.buckets) > 0 {
-				newKey := m.textInput.Value()
-				if newKey != "" {
-					err := m.db.PutValue(m.currentBucket, newKey, []byte(""))
+				newKey := parseBytes(m.textInput.Value())
+				if len(newKey) > 0 {
+					err := m.createKey(m.bucketPath, newKey)
 					if err != nil {
 						m.err = err
 						return m, nil
 					}
 					m.state = stateBuckets
-					return m, m.loadKeysAndValues(m.currentBucket)
+					return m, m.reloadKeys()
 				}
-			} else if m.state == stateEditValue && m.currentKey != "" {
-				newValue := m.textInput.Value()
-				if newValue != "" {
-					err := m.db.PutValue(m.currentBucket, m.currentKey, []byte(newValue))
-					if err != nil {
-						m.err = err
-						return m, nil
-					}
-					m.state = stateBuckets
-					return m, m.loadKeysAndValues(m.currentBucket)
+			} else if m.state == stateConfirmEdit {
+				return m, m.saveEdit()
+			} else if m.state == stateCommit {
+				return m, m.commitStaged()
+			} else if m.state == stateCompact {
+				return m, m.startCompact()
+			} else if m.state == stateExport {
+				if m.exporting || m.exportCount >= 0 {
+					return m, nil
 				}
-			} else if m.state == stateEditBucket && m.originalBucketName != "" {
-				newBucketName := m.textInput.Value()
-				if newBucketName != "" && newBucketName != m.originalBucketName {
-					err := m.db.RenameBucket(m.originalBucketName, newBucketName)
+				return m, m.startExport()
+			} else if m.state == stateEditBucket && len(m.originalBucketPath) > 0 {
+				path := m.originalBucketPath
+				oldName := path[len(path)-1]
+				newBucketName := string(parseBytes(m.textInput.Value()))
+				if newBucketName != "" && newBucketName != oldName {
+					err := m.makeEdit(edit{kind: editRenameBucket, path: path, newName: newBucketName})
 					if err != nil {
 						m.err = err
 						return m, nil
 					}
-					// Update the current bucket name to the new name
-					m.currentBucket = newBucketName
+					// Update the current bucket path if we renamed it or one of its parents
+					if len(m.bucketPath) >= len(path) && equalPaths(m.bucketPath[:len(path)], path) {
+						m.bucketPath = append([]string{}, m.bucketPath...)
+						m.bucketPath[len(path)-1] = newBucketName
+					}
 					m.state = stateBuckets
-					return m, m.loadBuckets
-				} else if newBucketName == m.originalBucketName {
+					return m, m.reloadAfterBucketChange(path)
+				} else if newBucketName == oldName {
 					// No change, just go back
 					m.state = stateBuckets
 					return m, nil
 				}
-			} else if m.state == stateEditKey && m.originalKeyName != "" {
-				newKeyName := m.textInput.Value()
-				if newKeyName != "" && newKeyName != m.originalKeyName {
-					err := m.db.RenameKey(m.currentBucket, m.originalKeyName, newKeyName)
+			} else if m.state == stateEditKey && len(m.originalKeyName) > 0 {
+				newKeyName := parseBytes(m.textInput.Value())
+				if len(newKeyName) > 0 && !bytes.Equal(newKeyName, m.originalKeyName) {
+					err := m.makeEdit(edit{kind: editRenameKey, path: m.bucketPath, key: m.originalKeyName, newKey: newKeyName})
 					if err != nil {
 						m.err = err
 						return m, nil
 					}
 					m.state = stateBuckets
-					return m, m.loadKeysAndValues(m.currentBucket)
-				} else if newKeyName == m.originalKeyName {
+					return m, m.reloadKeys()
+				} else if bytes.Equal(newKeyName, m.originalKeyName) {
 					// No change, just go back
 					m.state = stateBuckets
 					return m, nil
 				}
 			} else if m.state == stateBuckets && len(m.buckets) > 0 && len(m.table.Rows()) > 0 {
-				// Get the selected row from the table
-				selectedRow := m.table.SelectedRow()
-				if selectedRow != nil {
-					// The key is in the first column
-					m.currentKey = selectedRow[0]
-					// Get the current value to populate the text input
-					value, err := m.db.GetValue(m.currentBucket, m.currentKey)
-					if err != nil {
-						m.err = err
-						return m, nil
+				// Get the selected entry from the table
+				if selected, ok := m.selectedEntry(); ok {
+					if selected.isBucket {
+						// Drill into the nested bucket
+						return m, m.openBucket(m.childPath(selected.key))
 					}
-					// Set the text input value to the current value
-					m.textInput.SetValue(string(value))
-					// Transition to edit value state
-					m.state = stateEditValue
-					return m, textinput.Blink
+					if m.readOnly {
+						return m, m.openViewer(selected.key)
+					}
+					return m, m.editValue(selected.key)
 				}
 			}
 
@@ -486,6 +1168,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.table.SetWidth(msg.Width - 4)    // Account for margins
 		m.table.SetHeight(msg.Height - 10) // Account for header, tabs, help, etc.
+		m.searchTable.SetWidth(msg.Width - 4)
+		m.searchTable.SetHeight(msg.Height - 14) // Account for the search prompt too
+		if m.state == stateViewValue {
+			m.resizeViewer()
+		}
+		if m.state == stateStats {
+			m.viewer.Width = max(20, m.width-6)
+			m.viewer.Height = max(5, m.height-12)
+		}
+		m.resizeEditor()
 		return m, nil
 
 	case bucketsLoadedMsg:
@@ -496,20 +1188,26 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				for i, bucket := range m.buckets {
 					if bucket == m.newlyCreatedBucket {
 						m.activeTab = i
+						m.bucketPath = nil
 						m.newlyCreatedBucket = "" // Clear the flag
 						break
 					}
 				}
 			}
 
-			// If we have a current bucket name (e.g., after renaming), find its new index
-			if m.currentBucket != "" {
+			// If we have a current bucket (e.g., after renaming), find its new index
+			if len(m.bucketPath) > 0 {
+				found := false
 				for i, bucket := range m.buckets {
-					if bucket == m.currentBucket {
+					if bucket == m.bucketPath[0] {
 						m.activeTab = i
+						found = true
 						break
 					}
 				}
+				if !found {
+					m.bucketPath = nil
+				}
 			}
 
 			// Ensure activeTab is within bounds
@@ -520,27 +1218,106 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.activeTab = 0
 			}
 
-			m.currentBucket = m.buckets[m.activeTab]
-			return m, m.loadKeysAndValues(m.currentBucket)
+			if len(m.bucketPath) == 0 {
+				m.bucketPath = []string{m.buckets[m.activeTab]}
+			}
+			return m, m.openBucket(m.bucketPath)
+		}
+		m.bucketPath = nil
+		m.entries = nil
+		m.table.SetRows(nil)
+		return m, nil
+
+	case pageLoadedMsg:
+		// Ignore results superseded by a later load, e.g. for a bucket we
+		// have already navigated away from
+		if msg.seq != m.pageSeq {
+			return m, nil
+		}
+		m.loading = false
+		m.applyPage(msg)
+		return m, m.loadMore()
+
+	case openedMsg:
+		return m, m.applyOpened(msg)
+
+	case retryOpenMsg:
+		if m.state == stateLocked && m.lockWaiting {
+			return m, m.tryOpen(m.openReadOnly, false, true)
 		}
 		return m, nil
 
-	case keysLoadedMsg:
-		m.table.SetRows(msg.rows)
+	case checkedMsg:
+		m.checkReport = &msg.report
+		return m, nil
+
+	case statsLoadedMsg:
+		m.applyStats(msg)
+		return m, nil
+
+	case compactProgressMsg:
+		m.compactItems, m.compactBytes = msg.items, msg.bytes
+		return m, msg.next
+
+	case exportedMsg:
+		m.applyExport(msg)
+		return m, nil
+
+	case compactDoneMsg:
+		m.applyCompact(msg)
 		return m, nil
 
+	case snapshotTakenMsg:
+		return m, m.applySnapshot(msg)
+
+	case externalEditMsg:
+		return m, m.finishExternalEdit(msg)
+
+	case searchResultsMsg:
+		if msg.id != m.searchID {
+			return m, nil
+		}
+		m.applySearchResults(msg)
+		return m, msg.next
+
 	case error:
 		m.err = msg
+		m.loading = false
 		return m, nil
 	}
 
 	var cmd tea.Cmd
 	switch m.state {
 	case stateBuckets:
+		// Jumping to either end of a partially loaded bucket loads that end
+		if msg, ok := msg.(tea.KeyMsg); ok && len(m.entries) > 0 {
+			if key.Matches(msg, m.table.KeyMap.GotoTop) && m.hasPrev {
+				return m, m.loadKeysAndValues(m.bucketPath)
+			}
+			if key.Matches(msg, m.table.KeyMap.GotoBottom) && m.hasNext {
+				m.loading = true
+				return m, m.loadPage(m.bucketPath, nil, pageSize, pageLast)
+			}
+		}
 		m.table, cmd = m.table.Update(msg)
+		cmd = tea.Batch(cmd, m.loadMore())
+
+	case stateViewValue:
+		m.viewer, cmd = m.viewer.Update(msg)
+
+	case stateEditValue:
+		m.editor, cmd = m.editor.Update(msg)
+
+	case stateConfirmEdit, stateStats, stateHistory, stateCommit:
+		m.viewer, cmd = m.viewer.Update(msg)
 
-	case stateCreateBucket, stateCreateKey, stateEditBucket, stateEditKey, stateEditValue:
+	case stateCreateBucket, stateCreateKey, stateEditBucket, stateEditKey, stateFilter, stateCompact:
 		m.textInput, cmd = m.textInput.Update(msg)
+
+	case stateExport:
+		if m.typing() {
+			m.textInput, cmd = m.textInput.Update(msg)
+		}
 	}
 
 	return m, cmd
@@ -548,10 +1325,27 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *Model) View() string {
 	var s strings.Builder
+	currentBucket := displayPath(m.bucketPath)
 
 	// Title
-	s.WriteString(m.styles.Title.Render("BoltDB TUI - " + m.db.Path))
+	title := "BoltDB TUI - " + m.path
+	if m.snapshot != nil {
+		title += " " + m.styles.Badge.Render("SNAPSHOT")
+	} else if m.readOnly {
+		title += " " + m.styles.Badge.Render("RO")
+	} else if m.staging {
+		title += " " + m.styles.Badge.Render("STAGING")
+	}
+	s.WriteString(m.styles.Title.Render(title))
 	s.WriteString("\n\n")
+	if m.snapshot != nil {
+		s.WriteString(m.styles.Help.Render(m.snapshotStatus()))
+		s.WriteString("\n\n")
+	}
+	if m.checkReport != nil && !m.checkReport.OK() {
+		s.WriteString(m.checkBanner())
+		s.WriteString("\n\n")
+	}
 
 	// Error message
 	if m.err != nil {
@@ -560,51 +1354,108 @@ func (m *Model) View() string {
 
 	switch m.state {
 	case stateBuckets:
-		// Render tabs for buckets
+		// Render tabs for top-level buckets, or a breadcrumb inside nested ones
 		if len(m.buckets) > 0 {
-			s.WriteString(m.renderTabs())
+			if len(m.bucketPath) > 1 {
+				s.WriteString(m.renderBreadcrumb())
+			} else {
+				s.WriteString(m.renderTabs())
+			}
 			s.WriteString("\n\n")
+			if m.filterText != "" {
+				s.WriteString(fmt.Sprintf("Filter: %s (esc to clear)\n\n", m.filterText))
+			}
+			if c := m.codecs[pathKey(m.bucketPath)]; c != nil {
+				s.WriteString(fmt.Sprintf("Codec: %s (c to switch)\n\n", c.Name()))
+			}
+			if m.staging {
+				s.WriteString(fmt.Sprintf("Staged: %d changes (C to review and commit)\n\n", len(m.staged)))
+			}
 
 			// Render table for keys and values
 			s.WriteString(m.table.View())
+			if m.hasPrev || m.hasNext {
+				s.WriteString("\n" + m.styles.Help.Render(m.pageStatus()))
+			}
 		} else {
-			s.WriteString("No buckets found. Press 'n' to create a new bucket.")
+			if m.readOnly {
+				s.WriteString("No buckets found.")
+			} else {
+				s.WriteString("No buckets found. Press 'ctrl+t' to create a new bucket.")
+			}
 		}
 
 	case stateCreateBucket:
-		s.WriteString("Create new bucket:\n\n")
+		if len(m.parentPath) > 0 {
+			s.WriteString(fmt.Sprintf("Create new bucket in '%s':\n\n", displayPath(m.parentPath)))
+		} else {
+			s.WriteString("Create new bucket:\n\n")
+		}
 		s.WriteString(m.textInput.View())
 
 	case stateCreateKey:
-		s.WriteString(fmt.Sprintf("Create new key in bucket '%s':\n\n", m.currentBucket))
+		s.WriteString(fmt.Sprintf("Create new key in bucket '%s':\n\n", currentBucket))
 		s.WriteString(m.textInput.View())
 
 	case stateEditValue:
-		s.WriteString(fmt.Sprintf("Edit value of key '%s' in bucket '%s':\n\n", m.currentKey, m.currentBucket))
-		s.WriteString(m.textInput.View())
+		s.WriteString(m.editorView())
+
+	case stateConfirmEdit:
+		s.WriteString(m.confirmEditView())
 
 	case stateEditBucket:
-		s.WriteString(fmt.Sprintf("Edit bucket name '%s':\n\n", m.originalBucketName))
+		s.WriteString(fmt.Sprintf("Edit bucket name '%s':\n\n", displayPath(m.originalBucketPath)))
 		s.WriteString(m.textInput.View())
 
 	case stateEditKey:
-		s.WriteString(fmt.Sprintf("Edit key name '%s' in bucket '%s':\n\n", m.originalKeyName, m.currentBucket))
+		s.WriteString(fmt.Sprintf("Edit key name '%s' in bucket '%s':\n\n", displayBytes(m.originalKeyName), currentBucket))
 		s.WriteString(m.textInput.View())
 
+	case stateSearch:
+		s.WriteString(m.searchView())
+
+	case stateLocked:
+		s.WriteString(m.lockView())
+
+	case stateStats:
+		s.WriteString(m.statsView())
+
+	case stateCompact, stateCompacting:
+		s.WriteString(m.compactView())
+
+	case stateExport:
+		s.WriteString(m.exportView())
+
+	case stateHistory:
+		s.WriteString(m.historyView())
+
+	case stateCommit:
+		s.WriteString(m.commitView())
+
+	case stateViewValue:
+		s.WriteString(m.viewerView())
+
+	case stateFilter:
+		s.WriteString(fmt.Sprintf("Filter keys in bucket '%s':\n\n", currentBucket))
+		s.WriteString(m.textInput.View())
+		s.WriteString("\n\n" + m.styles.Help.Render("Prefix: user:1234:   Range: from..to, from.., ..to   Empty: clear"))
+
 	case stateConfirmDelete:
-		s.WriteString(fmt.Sprintf("Are you sure you want to delete key '%s' from bucket '%s'?\n\n", m.deleteKey, m.currentBucket))
+		s.WriteString(fmt.Sprintf("Are you sure you want to delete key '%s' from bucket '%s'?\n\n", displayBytes(m.deleteKey), currentBucket))
 		s.WriteString("Press Enter to confirm, Esc to cancel")
 
 	case stateConfirmDeleteBucket:
-		s.WriteString(fmt.Sprintf("Are you sure you want to delete bucket '%s'?\n\n", m.deleteBucket))
-		s.WriteString("This will delete all keys and values in the bucket.\n")
+		s.WriteString(fmt.Sprintf("Are you sure you want to delete bucket '%s'?\n\n", displayPath(m.deleteBucketPath)))
+		s.WriteString("This will delete all keys, values and nested buckets in the bucket.\n")
 		s.WriteString("Press Enter to confirm, Esc to cancel")
 	}
 
-	// Help
-	if m.showHelp {
+	// Help, except on the lock screen which lists its own keys
+	switch {
+	case m.state == stateLocked:
+	case m.showHelp:
 		s.WriteString("\n\n" + m.help.View(m.keyMap))
-	} else {
+	default:
 		s.WriteString("\n\n" + m.styles.Help.Render(" Press ? for help "))
 	}
 
@@ -621,16 +1472,49 @@ func (m *Model) renderTabs() string {
 		} else {
 			style = m.styles.Tab
 		}
-		renderedTabs = append(renderedTabs, style.Render(bucket))
+		renderedTabs = append(renderedTabs, style.Render(displayBytes([]byte(bucket))))
 	}
 
 	return lipgloss.JoinHorizontal(lipgloss.Top, renderedTabs...)
 }
 
+// pageStatus tells the user that the table only shows part of the bucket
+func (m *Model) pageStatus() string {
+	var parts []string
+	if m.hasPrev {
+		parts = append(parts, "▲ more above")
+	}
+	if m.hasNext {
+		parts = append(parts, "▼ more below")
+	}
+	if m.loading {
+		parts = append(parts, "loading…")
+	}
+	return " " + strings.Join(parts, " · ") + " "
+}
+
+// renderBreadcrumb renders the current bucket path, highlighting the last part
+func (m *Model) renderBreadcrumb() string {
+	parts := make([]string, len(m.bucketPath))
+	for i, name := range m.bucketPath {
+		if i == len(m.bucketPath)-1 {
+			parts[i] = m.styles.ActiveTab.Render(displayBytes([]byte(name)))
+		} else {
+			parts[i] = m.styles.Tab.Render(displayBytes([]byte(name)))
+		}
+	}
+	return strings.Join(parts, m.styles.Breadcrumb.Render("›"))
+}
+
+// Close closes the database and removes the snapshot being browsed, if any
 func (m *Model) Close() {
 	if m.db != nil {
 		m.db.Close()
 	}
+	if m.snapshot != nil {
+		m.snapshot.Remove()
+		m.snapshot = nil
+	}
 }
 
 // Add these methods to implement the help.KeyMap interface
@@ -638,8 +1522,8 @@ func (m *Model) Close() {
 // ShortHelp returns keybindings to be shown in the short help view.
 // It's part of the help.KeyMap interface.
 func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Down, k.Enter, k.Esc, k.NewTab, k.New,
-		k.Edit, k.EditBucket, k.Delete, k.DeleteBucket, k.Help, k.Quit}
+	return []key.Binding{k.Up, k.Down, k.Enter, k.Esc, k.NewTab, k.NewBucket, k.New,
+		k.Edit, k.EditBucket, k.Delete, k.DeleteBucket, k.Filter, k.Search, k.View, k.Help, k.Quit}
 }
 
 // FullHelp returns keybindings for the expanded help view.
@@ -647,8 +1531,8 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Left, k.Right}, // first column
-		{k.Enter, k.Esc, k.NewTab, k.New, k.Edit, k.EditBucket, k.Delete, k.DeleteBucket}, // second column
-		{k.PrevTab, k.NextTab, k.SelectTab},                                               // third column
-		{k.Help, k.Quit},                                                                  // fourth column
+		{k.Enter, k.Esc, k.NewTab, k.NewBucket, k.New, k.Edit, k.EditBucket, k.Delete, k.DeleteBucket},                                           // second column
+		{k.PrevTab, k.NextTab, k.SelectTab, k.Filter, k.Search, k.View, k.HexView, k.External, k.Codec, k.Refresh, k.Stats, k.Compact, k.Export}, // third column
+		{k.Undo, k.Redo, k.History, k.Stage, k.Commit, k.Help, k.Quit},                                                                           // fourth column
 	}
 }
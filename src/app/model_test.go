@@ -0,0 +1,36 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// openTestModel returns a model on a new database holding values in
+// bucket a, with a open
+func openTestModel(t *testing.T, values map[string]string) *Model {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := &bolt.DB{Path: path}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateBucket([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		if err := db.PutValue([]string{"a"}, []byte(k), []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	m, err := New(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	m.bucketPath = []string{"a"}
+	return m
+}
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+)
+
+type searchMode int
+
+const (
+	searchSubstring searchMode = iota
+	searchRegex
+	searchFuzzy
+)
+
+func (s searchMode) String() string {
+	switch s {
+	case searchRegex:
+		return "regex"
+	case searchFuzzy:
+		return "fuzzy"
+	default:
+		return "substring"
+	}
+}
+
+const (
+	searchLimit   = 1000 // Hits kept before the search stops
+	searchBatch   = 50   // Hits delivered to the UI per message
+	snippetRadius = 24   // Bytes of value shown on each side of a match
+)
+
+// searchResult is a single hit of a global search
+type searchResult struct {
+	path     []string // Bucket holding the hit
+	key      []byte
+	isBucket bool
+}
+
+type searchResultsMsg struct {
+	id      int
+	results []searchResult
+	rows    []table.Row
+	done    bool
+	err     error
+	next    tea.Cmd // Waits for the following batch, nil once done
+}
+
+// searchHit is what the background walk sends to the UI
+type searchHit struct {
+	result searchResult
+	row    table.Row
+}
+
+// matcher reports whether a key or value matches, and where in the value
+type matcher struct {
+	mode    searchMode
+	query   []byte
+	pattern *regexp.Regexp
+}
+
+func newMatcher(mode searchMode, query string) (*matcher, error) {
+	m := &matcher{mode: mode, query: []byte(query)}
+	if mode == searchRegex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		m.pattern = re
+	}
+	return m, nil
+}
+
+// matchKey reports whether the key matches. Fuzzy matching is done on the
+// display form of keys only, as it matches nearly any long value.
+func (m *matcher) matchKey(k []byte) bool {
+	switch m.mode {
+	case searchRegex:
+		return m.pattern.Match(k)
+	case searchFuzzy:
+		return len(fuzzy.Find(string(m.query), []string{displayBytes(k)})) > 0
+	default:
+		return bytes.Contains(k, m.query)
+	}
+}
+
+// matchValue returns the offset of the first match in the value, or -1
+func (m *matcher) matchValue(v []byte) int {
+	switch m.mode {
+	case searchRegex:
+		if loc := m.pattern.FindIndex(v); loc != nil {
+			return loc[0]
+		}
+		return -1
+	case searchFuzzy:
+		return -1
+	default:
+		return bytes.Index(v, m.query)
+	}
+}
+
+// snippet returns the display form of the value around offset
+func snippet(v []byte, offset int) string {
+	start := max(0, offset-snippetRadius)
+	end := min(len(v), offset+2*snippetRadius)
+	s := displayBytes(v[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(v) {
+		s += "…"
+	}
+	return s
+}
+
+// startSearch cancels any running search and walks the whole database in
+// the background, streaming hits back through a channel
+func (m *Model) startSearch() tea.Cmd {
+	m.cancelSearch()
+	m.searchID++
+	m.searchQuery = m.searchInput.Value()
+	m.searchRanMode = m.searchMode
+	m.searchResults = nil
+	m.searchErr = nil
+	m.searchTable.SetRows(nil)
+	if m.searchQuery == "" {
+		return nil
+	}
+
+	match, err := newMatcher(m.searchMode, m.searchQuery)
+	if err != nil {
+		m.searchErr = err
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.searchCancel, m.searchDone = cancel, done
+	m.searching = true
+	hits := make(chan searchHit, searchBatch)
+	errs := make(chan error, 1)
+
+	// The walk keeps its own reference to the database, which the model may
+	// swap for another one, e.g. when refreshing a snapshot
+	db := m.db
+	go func() {
+		defer close(done)
+		defer close(hits)
+		found := 0
+		err := db.Walk(func(path []string, k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var where, preview string
+			keyHit := match.matchKey(k)
+			switch {
+			case keyHit && v == nil:
+				where = "bucket"
+			case keyHit:
+				where, preview = "key", snippet(v, 0)
+			case v == nil:
+				return nil
+			default:
+				offset := match.matchValue(v)
+				if offset < 0 {
+					return nil
+				}
+				where, preview = "value", snippet(v, offset)
+			}
+
+			if found >= searchLimit {
+				return errSearchLimit
+			}
+			found++
+
+			hit := searchHit{
+				result: searchResult{
+					path:     append([]string{}, path...),
+					key:      append([]byte{}, k...),
+					isBucket: v == nil,
+				},
+				row: table.Row{displayPath(path), displayBytes(k), where, preview},
+			}
+			select {
+			case hits <- hit:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil && err != context.Canceled {
+			errs <- err
+		}
+	}()
+
+	return waitForHits(m.searchID, hits, errs)
+}
+
+var errSearchLimit = fmt.Errorf("stopped after %d matches", searchLimit)
+
+// waitForHits blocks for the next hit, then collects whatever else is ready
+// so the UI is updated in batches rather than once per hit
+func waitForHits(id int, hits <-chan searchHit, errs <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		msg := searchResultsMsg{id: id}
+		for len(msg.results) < searchBatch {
+			var (
+				hit searchHit
+				ok  bool
+			)
+			if len(msg.results) == 0 {
+				hit, ok = <-hits
+			} else {
+				select {
+				case hit, ok = <-hits:
+				default:
+					msg.next = waitForHits(id, hits, errs)
+					return msg
+				}
+			}
+			if !ok {
+				// The walk is over, pick up its error if it failed
+				msg.done = true
+				select {
+				case msg.err = <-errs:
+				default:
+				}
+				return msg
+			}
+			msg.results = append(msg.results, hit.result)
+			msg.rows = append(msg.rows, hit.row)
+		}
+		msg.next = waitForHits(id, hits, errs)
+		return msg
+	}
+}
+
+// cancelSearch stops the background walk, if any, and waits for it to
+// return so that its read transaction is closed
+func (m *Model) cancelSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	if m.searchDone != nil {
+		<-m.searchDone
+		m.searchDone = nil
+	}
+	m.searching = false
+}
+
+// applySearchResults appends streamed hits to the results table
+func (m *Model) applySearchResults(msg searchResultsMsg) {
+	if msg.id != m.searchID {
+		return
+	}
+	first := len(m.searchResults) == 0
+	m.searchResults = append(m.searchResults, msg.results...)
+	m.searchTable.SetRows(append(m.searchTable.Rows(), msg.rows...))
+	if first {
+		m.searchTable.SetCursor(0)
+	}
+	if msg.done {
+		m.searching = false
+		m.searchCancel, m.searchDone = nil, nil
+		m.searchErr = msg.err
+	}
+}
+
+// openSearch shows the search prompt, keeping the previous results
+func (m *Model) openSearch() tea.Cmd {
+	m.state = stateSearch
+	m.searchInput.Focus()
+	return textinput.Blink
+}
+
+// jumpToResult opens the bucket of the selected hit with the cursor on it
+func (m *Model) jumpToResult() tea.Cmd {
+	i := m.searchTable.Cursor()
+	if i < 0 || i >= len(m.searchResults) {
+		return nil
+	}
+	hit := m.searchResults[i]
+	// The walk would keep its read transaction open while the bucket is
+	// browsed
+	m.cancelSearch()
+	m.state = stateBuckets
+	m.err = nil
+
+	path, at := hit.path, hit.key
+	if hit.isBucket {
+		// Bucket hits open the bucket itself
+		path, at = append(append([]string{}, hit.path...), string(hit.key)), nil
+	}
+	for i, bucket := range m.buckets {
+		if bucket == path[0] {
+			m.activeTab = i
+			break
+		}
+	}
+	// Start the window at the hit; scrolling up loads what comes before it
+	return m.openBucketAt(path, at)
+}
+
+// updateSearch handles keys while the search prompt is shown. Typing goes to
+// the prompt, while arrow keys move through the results.
+func (m *Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Quit):
+		return m, m.quit()
+
+	case key.Matches(msg, m.keyMap.Esc):
+		// Leaving the search stops it, the results found so far are kept
+		m.cancelSearch()
+		m.state = stateBuckets
+		return m, nil
+
+	case key.Matches(msg, m.keyMap.SearchMode):
+		m.searchMode = (m.searchMode + 1) % 3
+		return m, m.startSearch()
+
+	case key.Matches(msg, m.keyMap.Enter):
+		// Run the search when the query changed, otherwise jump to the hit
+		if m.searchInput.Value() != m.searchQuery || m.searchMode != m.searchRanMode || len(m.searchResults) == 0 {
+			return m, m.startSearch()
+		}
+		return m, m.jumpToResult()
+
+	case msg.Type == tea.KeyUp, msg.Type == tea.KeyDown, msg.Type == tea.KeyPgUp, msg.Type == tea.KeyPgDown:
+		var cmd tea.Cmd
+		m.searchTable, cmd = m.searchTable.Update(msg)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+// searchView renders the search prompt and the results found so far
+func (m *Model) searchView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Search all buckets (%s):\n\n", m.searchMode))
+	s.WriteString(m.searchInput.View())
+	s.WriteString("\n\n")
+
+	switch {
+	case m.searchErr == errSearchLimit:
+		s.WriteString(fmt.Sprintf("%d matches, %v\n\n", len(m.searchResults), m.searchErr))
+	case m.searchErr != nil:
+		s.WriteString(fmt.Sprintf("Search error: %v\n\n", m.searchErr))
+	case m.searching:
+		s.WriteString(fmt.Sprintf("Searching… %d matches so far\n\n", len(m.searchResults)))
+	case m.searchQuery != "":
+		s.WriteString(fmt.Sprintf("%d matches\n\n", len(m.searchResults)))
+	}
+
+	if len(m.searchResults) > 0 {
+		s.WriteString(m.searchTable.View())
+		s.WriteString("\n")
+	}
+	s.WriteString(m.styles.Help.Render(" enter: search/jump · tab: switch mode · ↑/↓: select · esc: back "))
+	return s.String()
+}
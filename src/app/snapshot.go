@@ -0,0 +1,88 @@
+package app
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// snapshotTakenMsg is sent when a refreshed snapshot is ready
+type snapshotTakenMsg struct {
+	snapshot *bolt.Snapshot
+	err      error
+}
+
+// refreshSnapshot takes a new snapshot of the database in the background
+func (m *Model) refreshSnapshot() tea.Cmd {
+	if m.snapshot == nil || m.snapshotting {
+		return nil
+	}
+	if err := m.snapshotBusy(); err != nil {
+		m.err = err
+		return nil
+	}
+	m.snapshotting = true
+	source := m.snapshot.Source
+	return func() tea.Msg {
+		snapshot, err := bolt.TakeSnapshot(source)
+		return snapshotTakenMsg{snapshot, err}
+	}
+}
+
+// applySnapshot switches to a refreshed snapshot, staying in the same bucket
+func (m *Model) applySnapshot(msg snapshotTakenMsg) tea.Cmd {
+	m.snapshotting = false
+	if msg.err != nil {
+		m.err = msg.err
+		return nil
+	}
+	// A job started while the snapshot was taken still reads the old one,
+	// which cannot be closed under it
+	if err := m.snapshotBusy(); err != nil {
+		msg.snapshot.Remove()
+		m.err = err
+		return nil
+	}
+	db := &bolt.DB{Path: msg.snapshot.Path, ReadOnly: true}
+	if err := db.Open(); err != nil {
+		msg.snapshot.Remove()
+		m.err = err
+		return nil
+	}
+	m.cancelSearch()
+	m.db.Close()
+	m.snapshot.Remove()
+	m.db, m.snapshot = db, msg.snapshot
+	m.err = nil
+	return m.loadBuckets
+}
+
+// snapshotBusy returns an error when a background job is reading the
+// snapshot, which must not be closed before it is done. Searches are
+// cancelled instead, and the integrity check does not run on snapshots.
+func (m *Model) snapshotBusy() error {
+	switch {
+	case m.statsLoading:
+		return fmt.Errorf("wait for the statistics to load before refreshing the snapshot")
+	case m.compacting:
+		return fmt.Errorf("wait for the compaction to finish before refreshing the snapshot")
+	case m.exporting:
+		return fmt.Errorf("wait for the export to finish before refreshing the snapshot")
+	}
+	return nil
+}
+
+// snapshotStatus tells the user that they are looking at a copy
+func (m *Model) snapshotStatus() string {
+	status := fmt.Sprintf("Snapshot taken at %s", m.snapshot.Taken.Format("2006-01-02 15:04:05"))
+	if m.snapshotting {
+		status += " · refreshing…"
+	} else {
+		status += " · R: refresh"
+	}
+	if m.snapshot.Warning != "" {
+		status += "\nWarning: " + m.snapshot.Warning
+	}
+	return status
+}
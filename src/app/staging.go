@@ -0,0 +1,309 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// stagedKey is the state of a key once the staged changes are applied
+type stagedKey struct {
+	value   []byte
+	deleted bool
+	created bool // The key does not exist in the database
+}
+
+// mark returns the change marker of the key in the table
+func (s stagedKey) mark() string {
+	switch {
+	case s.deleted:
+		return "-"
+	case s.created:
+		return "+"
+	default:
+		return "~"
+	}
+}
+
+// stagedKeys replays the staged changes of the bucket at path, returning the
+// keys they touch
+func (m *Model) stagedKeys(path []string) map[string]stagedKey {
+	keys := make(map[string]stagedKey)
+	put := func(key, value []byte, created bool) {
+		if prev, ok := keys[string(key)]; ok {
+			created = prev.created
+		}
+		keys[string(key)] = stagedKey{value: value, created: created}
+	}
+	del := func(key []byte) {
+		if prev, ok := keys[string(key)]; ok && prev.created {
+			delete(keys, string(key))
+			return
+		}
+		keys[string(key)] = stagedKey{deleted: true}
+	}
+	for _, e := range m.staged {
+		if !equalPaths(e.path, path) {
+			continue
+		}
+		switch e.kind {
+		case editPut:
+			put(e.key, e.new, e.created)
+		case editDelete:
+			del(e.key)
+		case editRenameKey:
+			del(e.key)
+			put(e.newKey, e.old, e.created)
+		}
+	}
+	return keys
+}
+
+// getValue returns the value of key as the staged changes leave it, nil
+// when the key does not exist
+func (m *Model) getValue(path []string, key []byte) ([]byte, error) {
+	if s, ok := m.stagedKeys(path)[string(key)]; ok {
+		return s.value, nil
+	}
+	return m.db.GetValue(path, key)
+}
+
+// stage adds an edit to the staged changes instead of writing it, checking
+// it against the keys as the changes staged before it leave them
+func (m *Model) stage(e edit) error {
+	switch e.kind {
+	case editPut, editDelete, editRenameKey:
+	default:
+		return fmt.Errorf("bucket changes cannot be staged, leave staging mode with S to make them")
+	}
+	current, err := m.getValue(e.path, e.key)
+	if err != nil {
+		return err
+	}
+	switch e.kind {
+	case editPut:
+		if (current == nil) != e.created || !bytes.Equal(current, e.old) {
+			return bolt.ErrValueChanged
+		}
+	case editDelete:
+		if current == nil {
+			return nil
+		}
+		e.old = current
+	case editRenameKey:
+		if current == nil {
+			return fmt.Errorf("key '%s' not found in bucket %s", displayBytes(e.key), displayPath(e.path))
+		}
+		target, err := m.getValue(e.path, e.newKey)
+		if err != nil {
+			return err
+		}
+		if target != nil {
+			return fmt.Errorf("key '%s' already exists in bucket %s", displayBytes(e.newKey), displayPath(e.path))
+		}
+		e.old, e.created = current, true
+	}
+	e.path = append([]string{}, e.path...)
+	m.staged = append(m.staged, e)
+	m.quitStaged = false
+	return nil
+}
+
+// applyTx makes a staged edit within the transaction committing the staged
+// changes. It fails when the key no longer holds the value it was staged
+// against, so that changes made meanwhile are not overwritten.
+func (e edit) applyTx(tx *bolt.Tx) error {
+	value, exists, isBucket := tx.Get(e.path, e.key)
+	if isBucket {
+		return fmt.Errorf("key '%s' is a bucket", displayBytes(e.key))
+	}
+	if e.kind == editPut && e.created {
+		if exists {
+			return bolt.ErrValueChanged
+		}
+	} else if !exists || !bytes.Equal(value, e.old) {
+		return bolt.ErrValueChanged
+	}
+
+	switch e.kind {
+	case editPut:
+		return tx.Put(e.path, e.key, e.new)
+	case editDelete:
+		return tx.Delete(e.path, e.key)
+	default:
+		if _, exists, _ := tx.Get(e.path, e.newKey); exists {
+			return fmt.Errorf("key '%s' already exists", displayBytes(e.newKey))
+		}
+		if err := tx.Put(e.path, e.newKey, e.old); err != nil {
+			return err
+		}
+		return tx.Delete(e.path, e.key)
+	}
+}
+
+// commitStaged writes the staged changes in a single transaction. Nothing
+// is written when one of them fails.
+func (m *Model) commitStaged() tea.Cmd {
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range m.staged {
+			if err := e.applyTx(tx); err != nil {
+				return fmt.Errorf("%s: %v", e, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		m.err = fmt.Errorf("nothing was committed, %v", err)
+		return nil
+	}
+
+	// Committed changes can be undone one by one like any other edit
+	for _, e := range m.staged {
+		m.record(e)
+	}
+	m.err = nil
+	m.staged = nil
+	m.state = stateBuckets
+	return m.reloadKeys()
+}
+
+// discardStaged drops the staged changes
+func (m *Model) discardStaged() tea.Cmd {
+	m.err = nil
+	m.staged = nil
+	m.state = stateBuckets
+	return m.reloadKeys()
+}
+
+// unstage drops the last staged change, which is how undo works while
+// staging
+func (m *Model) unstage() tea.Cmd {
+	if len(m.staged) == 0 {
+		m.err = fmt.Errorf("nothing staged to undo")
+		return nil
+	}
+	e := m.staged[len(m.staged)-1]
+	m.staged = m.staged[:len(m.staged)-1]
+	m.err = nil
+	at := e.key
+	if e.kind == editRenameKey {
+		at = e.newKey
+	}
+	if equalPaths(e.path, m.bucketPath) && len(m.entries) > 0 {
+		return m.reloadKeys()
+	}
+	for i, bucket := range m.buckets {
+		if bucket == e.path[0] {
+			m.activeTab = i
+		}
+	}
+	return m.openBucketAt(e.path, at)
+}
+
+// toggleStaging turns staging mode on, or off when nothing is staged
+func (m *Model) toggleStaging() tea.Cmd {
+	if m.staging && len(m.staged) > 0 {
+		m.err = fmt.Errorf("commit or discard the %d staged changes first (C)", len(m.staged))
+		return nil
+	}
+	m.err = nil
+	m.staging = !m.staging
+	return nil
+}
+
+// openCommit shows the staged changes for review
+func (m *Model) openCommit() tea.Cmd {
+	if len(m.staged) == 0 {
+		m.err = fmt.Errorf("nothing is staged")
+		return nil
+	}
+	m.err = nil
+	m.state = stateCommit
+	m.viewer.Width = max(20, m.width-6)
+	m.viewer.Height = max(5, m.height-12)
+	lines := make([]string, len(m.staged))
+	for i, e := range m.staged {
+		mark := "~"
+		switch {
+		case e.kind == editDelete:
+			mark = m.styles.DiffDelete.Render("-")
+		case e.kind == editPut && e.created:
+			mark = m.styles.DiffInsert.Render("+")
+		case e.kind == editRenameKey:
+			mark = "→"
+		}
+		lines[i] = fmt.Sprintf("%3d. %s %s", i+1, mark, e)
+	}
+	m.viewer.SetContent(strings.Join(lines, "\n"))
+	m.viewer.GotoTop()
+	return nil
+}
+
+// commitView renders the staged changes waiting to be committed
+func (m *Model) commitView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Commit %d staged changes to %s in one transaction?\n\n", len(m.staged), m.path))
+	s.WriteString(m.viewer.View())
+	s.WriteString("\n")
+	s.WriteString(m.styles.Help.Render(" enter: commit · D: discard all · ↑/↓ scroll · esc: back "))
+	return s.String()
+}
+
+// stagePage lays the staged changes of a bucket over a page read from the
+// database. Changed values replace the stored ones, and created keys that
+// fall within the page are merged in, keeping the page within limit.
+func stagePage(page bolt.Page, keys map[string]stagedKey, r bolt.Range, from []byte, limit int, backward bool) bolt.Page {
+	onPage := make(map[string]bool, len(page.Entries))
+	for i, e := range page.Entries {
+		onPage[string(e.Key)] = true
+		if s, ok := keys[string(e.Key)]; ok && !e.IsBucket && !s.deleted {
+			page.Entries[i].Value = s.value
+		}
+	}
+
+	// Created keys belong on the page between its bounds, and past the end
+	// it reads up to when there is nothing more in the database
+	var first, last []byte
+	if len(page.Entries) > 0 {
+		first, last = page.Entries[0].Key, page.Entries[len(page.Entries)-1].Key
+	}
+	added := false
+	for k, s := range keys {
+		key := []byte(k)
+		if !s.created || s.deleted || onPage[k] || !r.Contains(key) {
+			continue
+		}
+		switch {
+		case !backward && from != nil && bytes.Compare(key, from) < 0:
+			page.HasPrev = true
+		case backward && from != nil && bytes.Compare(key, from) >= 0:
+			page.HasNext = true
+		case !backward && page.HasNext && (last == nil || bytes.Compare(key, last) > 0):
+		case backward && page.HasPrev && (first == nil || bytes.Compare(key, first) < 0):
+		default:
+			page.Entries = append(page.Entries, bolt.Entry{Key: key, Value: s.value})
+			added = true
+		}
+	}
+	if !added {
+		return page
+	}
+
+	sort.Slice(page.Entries, func(i, j int) bool {
+		return bytes.Compare(page.Entries[i].Key, page.Entries[j].Key) < 0
+	})
+	if extra := len(page.Entries) - limit; extra > 0 {
+		if backward {
+			page.Entries = page.Entries[extra:]
+			page.HasPrev = true
+		} else {
+			page.Entries = page.Entries[:limit]
+			page.HasNext = true
+		}
+	}
+	return page
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+func TestStagePage(t *testing.T) {
+	page := func(hasPrev, hasNext bool, keys ...string) bolt.Page {
+		p := bolt.Page{HasPrev: hasPrev, HasNext: hasNext}
+		for _, k := range keys {
+			p.Entries = append(p.Entries, bolt.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+		return p
+	}
+	created := func(keys ...string) map[string]stagedKey {
+		staged := make(map[string]stagedKey)
+		for _, k := range keys {
+			staged[k] = stagedKey{value: []byte("new"), created: true}
+		}
+		return staged
+	}
+
+	tests := []struct {
+		name     string
+		page     bolt.Page
+		staged   map[string]stagedKey
+		r        bolt.Range
+		from     string
+		limit    int
+		backward bool
+		want     string // Keys and values of the page, in order
+		wantPrev bool
+		wantNext bool
+	}{
+		{
+			name:   "created between keys",
+			page:   page(false, false, "b", "d"),
+			staged: created("c"),
+			limit:  10,
+			want:   "b=v c=new d=v",
+		},
+		{
+			name:     "created past a page with more after it",
+			page:     page(false, true, "b", "d"),
+			staged:   created("e"),
+			limit:    10,
+			want:     "b=v d=v",
+			wantNext: true,
+		},
+		{
+			name:   "created past the last page",
+			page:   page(false, false, "b", "d"),
+			staged: created("e"),
+			limit:  10,
+			want:   "b=v d=v e=new",
+		},
+		{
+			name:     "created before the start of the page",
+			page:     page(false, false, "b", "d"),
+			staged:   created("a"),
+			from:     "b",
+			limit:    10,
+			want:     "b=v d=v",
+			wantPrev: true,
+		},
+		{
+			name:     "created before the end of a backward page",
+			page:     page(true, false, "b", "d"),
+			staged:   created("e"),
+			from:     "e",
+			limit:    10,
+			backward: true,
+			want:     "b=v d=v",
+			wantPrev: true,
+			wantNext: true,
+		},
+		{
+			name:     "full page",
+			page:     page(false, false, "b", "d"),
+			staged:   created("c"),
+			limit:    2,
+			want:     "b=v c=new",
+			wantNext: true,
+		},
+		{
+			name:     "full backward page",
+			page:     page(false, false, "b", "d"),
+			staged:   created("c"),
+			from:     "e",
+			limit:    2,
+			backward: true,
+			want:     "c=new d=v",
+			wantPrev: true,
+		},
+		{
+			name:   "changed and deleted keys",
+			page:   page(false, false, "b", "d"),
+			staged: map[string]stagedKey{"b": {value: []byte("new")}, "d": {deleted: true}},
+			limit:  10,
+			want:   "b=new d=v",
+		},
+		{
+			name:   "created outside the filter",
+			page:   page(false, false, "ab", "ad"),
+			staged: created("ac", "b"),
+			r:      bolt.Range{Prefix: []byte("a")},
+			limit:  10,
+			want:   "ab=v ac=new ad=v",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from []byte
+			if tt.from != "" {
+				from = []byte(tt.from)
+			}
+			got := stagePage(tt.page, tt.staged, tt.r, from, tt.limit, tt.backward)
+			var entries []string
+			for _, e := range got.Entries {
+				entries = append(entries, string(e.Key)+"="+string(e.Value))
+			}
+			if strings.Join(entries, " ") != tt.want {
+				t.Errorf("entries = %q, want %q", strings.Join(entries, " "), tt.want)
+			}
+			if got.HasPrev != tt.wantPrev || got.HasNext != tt.wantNext {
+				t.Errorf("prev, next = %v, %v, want %v, %v", got.HasPrev, got.HasNext, tt.wantPrev, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCommitStaged(t *testing.T) {
+	tests := []struct {
+		name      string
+		meanwhile map[string]string // Values written after staging
+		wantErr   bool
+		want      map[string]string // Values after the commit, "" when missing
+	}{
+		{
+			name: "committed",
+			want: map[string]string{"k": "", "n": "new", "m": "w"},
+		},
+		{
+			name:      "conflict",
+			meanwhile: map[string]string{"m": "other"},
+			wantErr:   true,
+			want:      map[string]string{"k": "v", "n": "", "m": "other"},
+		},
+		{
+			name:      "created meanwhile",
+			meanwhile: map[string]string{"n": "other"},
+			wantErr:   true,
+			want:      map[string]string{"k": "v", "n": "other", "m": "v"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openTestModel(t, map[string]string{"k": "v", "m": "v"})
+			m.staging = true
+			a := []string{"a"}
+			if err := m.deleteValue(a, []byte("k")); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.makeEdit(edit{kind: editPut, path: a, key: []byte("n"), new: []byte("new"), created: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.makeEdit(edit{kind: editPut, path: a, key: []byte("m"), old: []byte("v"), new: []byte("w")}); err != nil {
+				t.Fatal(err)
+			}
+			if len(m.staged) != 3 {
+				t.Fatalf("%d changes staged, want 3", len(m.staged))
+			}
+			for k, v := range tt.meanwhile {
+				if err := m.db.PutValue(a, []byte(k), []byte(v)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m.commitStaged()
+			if (m.err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want an error: %v", m.err, tt.wantErr)
+			}
+			if tt.wantErr != (len(m.staged) == 3) {
+				t.Errorf("%d changes left staged", len(m.staged))
+			}
+			for k, want := range tt.want {
+				v, err := m.db.GetValue(a, []byte(k))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(v) != want {
+					t.Errorf("%s = %q, want %q", k, v, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// statsLoadedMsg carries the statistics of the database, or why they could
+// not be read
+type statsLoadedMsg struct {
+	stats bolt.Stats
+	err   error
+}
+
+// openStats shows the statistics view and gathers the statistics in the
+// background, as that reads every page of the database. Reopening the view
+// while they are read waits for the same run.
+func (m *Model) openStats() tea.Cmd {
+	m.state = stateStats
+	m.viewer.Width = max(20, m.width-6)
+	m.viewer.Height = max(5, m.height-12)
+	if m.statsLoading {
+		return nil
+	}
+	m.statsLoading = true
+	m.viewer.SetContent("")
+	db := m.db
+	return func() tea.Msg {
+		stats, err := db.Stats()
+		return statsLoadedMsg{stats, err}
+	}
+}
+
+// applyStats shows the statistics once they are read, unless the view was
+// left in the meantime
+func (m *Model) applyStats(msg statsLoadedMsg) {
+	m.statsLoading = false
+	if m.state != stateStats {
+		return
+	}
+	if msg.err != nil {
+		m.err = fmt.Errorf("could not read the statistics: %v", msg.err)
+		m.state = stateBuckets
+		return
+	}
+	m.viewer.SetContent(msg.stats.String())
+	m.viewer.GotoTop()
+}
+
+// statsView renders the statistics view
+func (m *Model) statsView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Statistics of %s:\n\n", m.path))
+	if m.statsLoading {
+		s.WriteString("Reading pages…")
+		return s.String()
+	}
+	s.WriteString(m.viewer.View())
+	s.WriteString("\n")
+	s.WriteString(m.styles.Help.Render(fmt.Sprintf(" %3.f%% · ↑/↓ scroll · esc: back ", m.viewer.ScrollPercent()*100)))
+	return s.String()
+}
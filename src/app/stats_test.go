@@ -0,0 +1,58 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		left      bool // The view was left before the statistics were read
+		err       error
+		wantState state
+		wantErr   bool
+		wantStats bool
+	}{
+		{name: "loaded", wantState: stateStats, wantStats: true},
+		{name: "failed", err: errors.New("broken page"), wantState: stateBuckets, wantErr: true},
+		{name: "loaded after leaving", left: true, wantState: stateHistory},
+		{name: "failed after leaving", left: true, err: errors.New("broken page"), wantState: stateHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openTestModel(t, nil)
+			cmd := m.openStats()
+			if m.openStats() != nil {
+				t.Fatal("reopening the view started a second run")
+			}
+			msg := cmd().(statsLoadedMsg)
+			if tt.err != nil {
+				msg = statsLoadedMsg{err: tt.err}
+			}
+			if tt.left {
+				m.state = stateHistory
+				m.viewer.SetContent("history")
+			}
+
+			m.applyStats(msg)
+			if m.statsLoading {
+				t.Error("statistics are still loading")
+			}
+			if m.state != tt.wantState {
+				t.Errorf("state = %v, want %v", m.state, tt.wantState)
+			}
+			if (m.err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want an error: %v", m.err, tt.wantErr)
+			}
+			content := m.viewer.View()
+			if tt.wantStats != strings.Contains(content, "File size") {
+				t.Errorf("viewer shows %q", content)
+			}
+			if tt.left && !strings.Contains(content, "history") {
+				t.Errorf("viewer content was replaced by %q", content)
+			}
+		})
+	}
+}
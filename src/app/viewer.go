@@ -0,0 +1,208 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+const viewerLimit = 4 << 20 // Bytes of a value rendered in the viewer
+
+// openViewer loads the full value of key and shows it in the viewer pane
+func (m *Model) openViewer(key []byte) tea.Cmd {
+	value, err := m.getValue(m.bucketPath, key)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.viewKey = key
+	m.viewValue = value
+	m.viewKind = valueKind(value)
+	// Values that are not text are unreadable without a hex dump
+	m.viewHex = !utf8.Valid(value)
+	m.viewDecoded = ""
+	c, tree, err := m.decodeValue(value)
+	if err != nil {
+		m.viewKind = err.Error()
+	} else if c != nil {
+		m.viewKind = c.Name()
+		m.viewDecoded = codec.Format(tree, "  ")
+		m.viewHex = false
+	}
+	m.state = stateViewValue
+	m.resizeViewer()
+	m.viewer.GotoTop()
+	return nil
+}
+
+// resizeViewer fits the viewer to the window and re-renders the value, since
+// wrapping depends on the width
+func (m *Model) resizeViewer() {
+	width := max(20, m.width-6)
+	m.viewer.Width = width
+	m.viewer.Height = max(5, m.height-12) // Account for header, title, help, etc.
+	m.viewer.SetContent(m.renderValue(width))
+}
+
+// renderValue formats the value for the viewer: JSON is indented and
+// highlighted, text is shown as is and anything else in its escaped form.
+// Long lines are wrapped to width.
+func (m *Model) renderValue(width int) string {
+	value := m.viewValue
+	if len(value) > viewerLimit {
+		value = value[:viewerLimit]
+	}
+
+	var content string
+	switch {
+	case m.viewHex:
+		// Hex dumps have a fixed width and are not wrapped
+		return hexDump(value, m.styles)
+	case m.viewDecoded != "":
+		content = highlightJSON(m.viewDecoded, m.styles)
+	case m.viewKind == "empty":
+		content = m.styles.Help.Render("(empty value)")
+	case m.viewKind == "JSON":
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, value, "", "  "); err != nil {
+			content = string(value)
+		} else {
+			content = highlightJSON(indented.String(), m.styles)
+		}
+	case m.viewKind == "text":
+		content = string(value)
+	default:
+		content = displayBytes(value)
+	}
+
+	return lipgloss.NewStyle().Width(width).Render(content)
+}
+
+// valueKind describes how the viewer renders a value
+func valueKind(value []byte) string {
+	switch {
+	case len(value) == 0:
+		return "empty"
+	case json.Valid(value):
+		return "JSON"
+	case codec.IsText(value):
+		return "text"
+	default:
+		return "binary"
+	}
+}
+
+// hexDump formats b like xxd: the offset, 16 bytes in groups of two and the
+// printable characters of each line, with dots for the rest
+func hexDump(b []byte, st Styles) string {
+	var out strings.Builder
+	for offset := 0; offset < len(b); offset += 16 {
+		line := b[offset:min(offset+16, len(b))]
+		out.WriteString(st.Help.Render(fmt.Sprintf("%08x:", offset)))
+		for i := 0; i < 16; i++ {
+			if i%2 == 0 {
+				out.WriteByte(' ')
+			}
+			if i < len(line) {
+				out.WriteString(fmt.Sprintf("%02x", line[i]))
+			} else {
+				out.WriteString("  ")
+			}
+		}
+		out.WriteString("  ")
+		for _, c := range line {
+			if c >= 0x20 && c < 0x7f {
+				out.WriteByte(c)
+			} else {
+				out.WriteString(st.Help.Render("."))
+			}
+		}
+		if offset+16 < len(b) {
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+// highlightJSON colours the tokens of an indented JSON document. The input
+// must be valid JSON.
+func highlightJSON(s string, st Styles) string {
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			// Find the closing quote, skipping escaped characters
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(s))
+			token := s[i:j]
+			if strings.HasPrefix(strings.TrimLeft(s[j:], " "), ":") {
+				out.WriteString(st.JSONKey.Render(token))
+			} else {
+				out.WriteString(st.JSONString.Render(token))
+			}
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+			out.WriteString(st.JSONNumber.Render(s[i:j]))
+			i = j
+		case c == 't' || c == 'f' || c == 'n':
+			j := i + 1
+			for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+				j++
+			}
+			out.WriteString(st.JSONLiteral.Render(s[i:j]))
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// toggleHex switches the viewed value between its hex dump and text forms
+func (m *Model) toggleHex() {
+	m.viewHex = !m.viewHex
+	m.resizeViewer()
+	m.viewer.GotoTop()
+}
+
+// viewerView renders the value viewer pane
+func (m *Model) viewerView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Value of key '%s' in bucket '%s' (%d bytes, %s):\n\n",
+		displayBytes(m.viewKey), displayPath(m.bucketPath), len(m.viewValue), m.viewKind))
+	s.WriteString(m.viewer.View())
+	s.WriteString("\n")
+
+	mode := "hex"
+	if m.viewHex {
+		mode = "text"
+	}
+	edit := " enter/e: edit ·"
+	if m.readOnly {
+		edit = ""
+	}
+	status := fmt.Sprintf(" %3.f%% · ↑/↓ scroll · x: %s view · c: codec ·%s esc: back ", m.viewer.ScrollPercent()*100, mode, edit)
+	if len(m.viewValue) > viewerLimit {
+		status = fmt.Sprintf(" showing the first %d bytes ·", viewerLimit) + status
+	}
+	s.WriteString(m.styles.Help.Render(status))
+	return s.String()
+}
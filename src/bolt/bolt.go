@@ -1,21 +1,52 @@
 package bolt
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
 )
 
+// ErrValueChanged is returned by CompareAndPut when the stored value is not
+// the expected one
+var ErrValueChanged = errors.New("value was modified in the meantime")
+
 // DB represents a BoltDB database wrapper
 type DB struct {
-	Path string
-	db   *bolt.DB
+	Path     string
+	ReadOnly bool          // Open with a shared lock, so other readers are not blocked
+	Timeout  time.Duration // How long Open waits for the lock, 1 second when zero
+	db       *bolt.DB
+}
+
+// PathString returns a human readable form of a bucket path
+func PathString(path []string) string {
+	return strings.Join(path, "/")
 }
 
 // Open opens the BoltDB database
 func (b *DB) Open() error {
-	db, err := bolt.Open(b.Path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if b.ReadOnly {
+		// Bolt would create the file, and then fail to initialise it
+		if _, err := os.Stat(b.Path); err != nil {
+			return fmt.Errorf("could not open db: %v", err)
+		}
+	}
+	timeout := b.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Second
+	}
+	db, err := bolt.Open(b.Path, 0600, &bolt.Options{Timeout: timeout, ReadOnly: b.ReadOnly})
+	if err == bolt.ErrTimeout {
+		return fmt.Errorf("could not open db: %w", ErrLocked)
+	}
+	if err == bolt.ErrChecksum {
+		return fmt.Errorf("could not open db: %w: %v", ErrCorrupt, err)
+	}
 	if err != nil {
 		return fmt.Errorf("could not open db: %v", err)
 	}
@@ -31,28 +62,76 @@ func (b *DB) Close() error {
 	return nil
 }
 
-// GetBuckets returns all buckets in the database
-func (b *DB) GetBuckets() ([]string, error) {
+// bucketAt walks the bucket path from the root of the transaction
+func bucketAt(tx *bolt.Tx, path []string) (*bolt.Bucket, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("bucket path cannot be empty")
+	}
+	bucket := tx.Bucket([]byte(path[0]))
+	if bucket == nil {
+		return nil, fmt.Errorf("bucket %s not found", path[0])
+	}
+	for i := 1; i < len(path); i++ {
+		bucket = bucket.Bucket([]byte(path[i]))
+		if bucket == nil {
+			return nil, fmt.Errorf("bucket %s not found", PathString(path[:i+1]))
+		}
+	}
+	return bucket, nil
+}
+
+// copyBucket copies all key-value pairs and nested buckets from src into dst
+func copyBucket(dst, src *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			child, err := dst.CreateBucket(k)
+			if err != nil {
+				return err
+			}
+			return copyBucket(child, src.Bucket(k))
+		}
+		return dst.Put(k, v)
+	})
+}
+
+// GetBuckets returns the buckets nested at path, or the top-level buckets
+// when path is empty
+func (b *DB) GetBuckets(path []string) ([]string, error) {
 	var buckets []string
 	err := b.db.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
-			buckets = append(buckets, string(name))
+		if len(path) == 0 {
+			return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				buckets = append(buckets, string(name))
+				return nil
+			})
+		}
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				buckets = append(buckets, string(k))
+			}
 			return nil
 		})
 	})
 	return buckets, err
 }
 
-// GetKeysInBucket returns all keys in a bucket
-func (b *DB) GetKeysInBucket(bucketName string) ([]string, error) {
-	var keys []string
+// GetKeysInBucket returns all keys in a bucket, skipping nested buckets
+func (b *DB) GetKeysInBucket(path []string) ([][]byte, error) {
+	var keys [][]byte
 	err := b.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
 		}
-		return bucket.ForEach(func(k, _ []byte) error {
-			keys = append(keys, string(k))
+		return bucket.ForEach(func(k, v []byte) error {
+			if v != nil {
+				// Copy the key since it is only valid for the life of the transaction
+				keys = append(keys, append([]byte{}, k...))
+			}
 			return nil
 		})
 	})
@@ -60,59 +139,159 @@ func (b *DB) GetKeysInBucket(bucketName string) ([]string, error) {
 }
 
 // GetValue returns the value for a key in a bucket
-func (b *DB) GetValue(bucketName, key string) ([]byte, error) {
+func (b *DB) GetValue(path []string, key []byte) ([]byte, error) {
 	var value []byte
 	err := b.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		// Copy the value since it is only valid for the life of the transaction
+		if v := bucket.Get(key); v != nil {
+			value = append([]byte{}, v...)
 		}
-		value = bucket.Get([]byte(key))
 		return nil
 	})
 	return value, err
 }
 
-// CreateBucket creates a new bucket
-func (b *DB) CreateBucket(bucketName string) error {
+// CreateBucket creates a new bucket at path, creating any missing parents
+func (b *DB) CreateBucket(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
 	return b.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
-		return err
+		bucket, err := tx.CreateBucketIfNotExists([]byte(path[0]))
+		if err != nil {
+			return err
+		}
+		for _, name := range path[1:] {
+			bucket, err = bucket.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
-// DeleteBucket deletes a bucket
-func (b *DB) DeleteBucket(bucketName string) error {
+// DeleteBucket deletes the bucket at path along with everything nested in it
+func (b *DB) DeleteBucket(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
 	return b.db.Update(func(tx *bolt.Tx) error {
-		return tx.DeleteBucket([]byte(bucketName))
+		name := []byte(path[len(path)-1])
+		if len(path) == 1 {
+			return tx.DeleteBucket(name)
+		}
+		parent, err := bucketAt(tx, path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		return parent.DeleteBucket(name)
+	})
+}
+
+// DeleteEmptyBucket deletes the bucket at path, refusing when it holds keys
+// or nested buckets
+func (b *DB) DeleteEmptyBucket(path []string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		if k, _ := bucket.Cursor().First(); k != nil {
+			return fmt.Errorf("bucket %s is no longer empty", PathString(path))
+		}
+		name := []byte(path[len(path)-1])
+		if len(path) == 1 {
+			return tx.DeleteBucket(name)
+		}
+		parent, _ := bucketAt(tx, path[:len(path)-1])
+		return parent.DeleteBucket(name)
 	})
 }
 
 // PutValue puts a value for a key in a bucket
-func (b *DB) PutValue(bucketName, key string, value []byte) error {
+func (b *DB) PutValue(path []string, key, value []byte) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+}
+
+// CompareAndPut puts value for key only if the key still holds old, checking
+// and writing in a single transaction. It returns ErrValueChanged otherwise,
+// including when the key was deleted.
+func (b *DB) CompareAndPut(path []string, key, old, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		k, v := bucket.Cursor().Seek(key)
+		if !bytes.Equal(k, key) || v == nil || !bytes.Equal(v, old) {
+			return ErrValueChanged
+		}
+		return bucket.Put(key, value)
+	})
+}
+
+// PutNewValue puts a value for a key that does not exist yet. It returns
+// ErrValueChanged when the key exists.
+func (b *DB) PutNewValue(path []string, key, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		if k, _ := bucket.Cursor().Seek(key); bytes.Equal(k, key) {
+			return ErrValueChanged
 		}
-		return bucket.Put([]byte(key), value)
+		return bucket.Put(key, value)
+	})
+}
+
+// CompareAndDelete deletes a key only if it still holds old. It returns
+// ErrValueChanged otherwise.
+func (b *DB) CompareAndDelete(path []string, key, old []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		k, v := bucket.Cursor().Seek(key)
+		if !bytes.Equal(k, key) || v == nil || !bytes.Equal(v, old) {
+			return ErrValueChanged
+		}
+		return bucket.Delete(key)
 	})
 }
 
 // DeleteValue deletes a key from a bucket
-func (b *DB) DeleteValue(bucketName, key string) error {
+func (b *DB) DeleteValue(path []string, key []byte) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
 		}
-		return bucket.Delete([]byte(key))
+		return bucket.Delete(key)
 	})
 }
 
-// RenameBucket renames a bucket by creating a new bucket with the new name,
-// copying all key-value pairs, then deleting the old bucket
-func (b *DB) RenameBucket(oldName, newName string) error {
+// RenameBucket renames the bucket at path by creating a sibling bucket with
+// the new name, copying all key-value pairs and nested buckets, then deleting
+// the old bucket
+func (b *DB) RenameBucket(path []string, newName string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
+
+	oldName := path[len(path)-1]
 	if oldName == newName {
 		return nil // No change needed
 	}
@@ -123,70 +302,84 @@ func (b *DB) RenameBucket(oldName, newName string) error {
 
 	return b.db.Update(func(tx *bolt.Tx) error {
 		// Get the old bucket
-		oldBucket := tx.Bucket([]byte(oldName))
-		if oldBucket == nil {
-			return fmt.Errorf("bucket %s not found", oldName)
+		oldBucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+
+		// Top-level buckets live on the transaction, nested ones on their parent
+		var (
+			exists func([]byte) *bolt.Bucket
+			create func([]byte) (*bolt.Bucket, error)
+			remove func([]byte) error
+		)
+		if len(path) == 1 {
+			exists, create, remove = tx.Bucket, tx.CreateBucket, tx.DeleteBucket
+		} else {
+			parent, err := bucketAt(tx, path[:len(path)-1])
+			if err != nil {
+				return err
+			}
+			exists, create, remove = parent.Bucket, parent.CreateBucket, parent.DeleteBucket
 		}
 
 		// Check if new bucket already exists
-		if tx.Bucket([]byte(newName)) != nil {
+		if exists([]byte(newName)) != nil {
 			return fmt.Errorf("bucket %s already exists", newName)
 		}
 
 		// Create the new bucket
-		newBucket, err := tx.CreateBucketIfNotExists([]byte(newName))
+		newBucket, err := create([]byte(newName))
 		if err != nil {
 			return err
 		}
 
-		// Copy all key-value pairs from old bucket to new bucket
-		err = oldBucket.ForEach(func(k, v []byte) error {
-			return newBucket.Put(k, v)
-		})
-		if err != nil {
+		// Copy everything from old bucket to new bucket
+		if err := copyBucket(newBucket, oldBucket); err != nil {
 			return err
 		}
 
 		// Delete the old bucket
-		return tx.DeleteBucket([]byte(oldName))
+		return remove([]byte(oldName))
 	})
 }
 
 // RenameKey renames a key within a bucket by copying the value to the new key
 // and deleting the old key
-func (b *DB) RenameKey(bucketName, oldKey, newKey string) error {
-	if oldKey == newKey {
+func (b *DB) RenameKey(path []string, oldKey, newKey []byte) error {
+	if bytes.Equal(oldKey, newKey) {
 		return nil // No change needed
 	}
 
-	if newKey == "" {
+	if len(newKey) == 0 {
 		return fmt.Errorf("new key name cannot be empty")
 	}
 
 	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
 		}
+		bucketName := PathString(path)
 
 		// Get the value of the old key
-		value := bucket.Get([]byte(oldKey))
+		value := bucket.Get(oldKey)
 		if value == nil {
-			return fmt.Errorf("key %s not found in bucket %s", oldKey, bucketName)
+			return fmt.Errorf("key %q not found in bucket %s", oldKey, bucketName)
 		}
 
 		// Check if new key already exists
-		if bucket.Get([]byte(newKey)) != nil {
-			return fmt.Errorf("key %s already exists in bucket %s", newKey, bucketName)
+		if bucket.Get(newKey) != nil {
+			return fmt.Errorf("key %q already exists in bucket %s", newKey, bucketName)
 		}
 
 		// Put the value with the new key
-		err := bucket.Put([]byte(newKey), value)
+		err = bucket.Put(newKey, value)
 		if err != nil {
 			return err
 		}
 
 		// Delete the old key
-		return bucket.Delete([]byte(oldKey))
+		return bucket.Delete(oldKey)
 	})
 }
@@ -0,0 +1,245 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrCorrupt is returned when a database cannot be opened because it is damaged
+var ErrCorrupt = errors.New("database is corrupt")
+
+// checkLimit is the number of problems kept by Check, the rest are counted
+const checkLimit = 100
+
+// CheckReport lists the problems found by Check
+type CheckReport struct {
+	Pages    int     // Pages of the file that were checked
+	Problems []error // The first problems found
+	Count    int     // Number of problems found, including those not kept
+}
+
+// OK reports whether the database passed the check
+func (r CheckReport) OK() bool {
+	return r.Count == 0
+}
+
+// add records a problem
+func (r *CheckReport) add(err error) {
+	if r.Count < checkLimit {
+		r.Problems = append(r.Problems, err)
+	}
+	r.Count++
+}
+
+// Check verifies the pages of the database: that the file holds every page
+// in use, that each page is either free or used once by a bucket, and that
+// bucket pages are branch or leaf pages. Problems found are reported, an
+// error is returned only when the check could not run.
+//
+// Bolt's own check follows page references without bounds checks and
+// crashes the program on some damage, so the tree is first walked from the
+// file with every reference checked, and Bolt's check only runs when that
+// walk finds nothing wrong.
+func (b *DB) Check() (CheckReport, error) {
+	var report CheckReport
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return report, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return report, err
+	}
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		pageSize := tx.DB().Info().PageSize
+		report.Pages = int(tx.Size() / int64(pageSize))
+		if info.Size() < tx.Size() {
+			report.add(fmt.Errorf("file is truncated: %d bytes, but %d pages of %d bytes are in use",
+				info.Size(), report.Pages, pageSize))
+			return nil
+		}
+
+		c := &pageChecker{file: f, pageSize: pageSize, pages: uint64(report.Pages), report: &report, seen: map[uint64]bool{}}
+		if err := c.check(uint64(tx.ID())); err != nil || !report.OK() {
+			return err
+		}
+		// Drain the channel so the check is over before the transaction ends
+		for err := range tx.Check() {
+			report.add(err)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// Page layout of Bolt files, see page.go, bucket.go and db.go in Bolt
+const (
+	pageHeaderSize   = 16 // id, flags, count and overflow
+	pageElementSize  = 16 // Size of branch and leaf page elements
+	branchPageFlag   = 0x01
+	leafPageFlag     = 0x02
+	freelistPageFlag = 0x10
+	bucketLeafFlag   = 0x01
+	bucketHeaderSize = 16 // root and sequence
+)
+
+// pageChecker walks the page tree of a database from its file
+type pageChecker struct {
+	file     *os.File
+	pageSize int
+	pages    uint64 // Pages in use, ids above are out of bounds
+	report   *CheckReport
+	seen     map[uint64]bool
+}
+
+// check finds the meta page of the transaction txid and walks the tree
+// from its root bucket
+func (c *pageChecker) check(txid uint64) error {
+	for id := uint64(0); id < 2; id++ {
+		meta := make([]byte, 80)
+		if _, err := c.file.ReadAt(meta, int64(id)*int64(c.pageSize)); err != nil {
+			return err
+		}
+		if binary.NativeEndian.Uint64(meta[64:]) != txid {
+			continue
+		}
+		freelist := binary.NativeEndian.Uint64(meta[48:])
+		if p, err := c.page(freelist); err != nil {
+			return err
+		} else if p != nil && pageFlags(p) != freelistPageFlag {
+			c.report.add(fmt.Errorf("page %d: invalid freelist page type: %#x", freelist, pageFlags(p)))
+		}
+		delete(c.seen, freelist)
+		return c.tree(binary.NativeEndian.Uint64(meta[32:]))
+	}
+	return fmt.Errorf("no meta page for transaction %d", txid)
+}
+
+// page reads a page with its overflow, or reports why it cannot be read
+func (c *pageChecker) page(id uint64) ([]byte, error) {
+	if id < 2 || id >= c.pages {
+		c.report.add(fmt.Errorf("page %d: out of bounds: %d", id, c.pages))
+		return nil, nil
+	}
+	if c.seen[id] {
+		c.report.add(fmt.Errorf("page %d: multiple references", id))
+		return nil, nil
+	}
+	c.seen[id] = true
+
+	header := make([]byte, pageHeaderSize)
+	if _, err := c.file.ReadAt(header, int64(id)*int64(c.pageSize)); err != nil {
+		return nil, err
+	}
+	if got := binary.NativeEndian.Uint64(header); got != id {
+		c.report.add(fmt.Errorf("page %d: header has id %d", id, got))
+		return nil, nil
+	}
+	overflow := uint64(binary.NativeEndian.Uint32(header[12:]))
+	if id+overflow >= c.pages {
+		c.report.add(fmt.Errorf("page %d: overflow of %d pages out of bounds: %d", id, overflow, c.pages))
+		return nil, nil
+	}
+	p := make([]byte, (overflow+1)*uint64(c.pageSize))
+	if _, err := c.file.ReadAt(p, int64(id)*int64(c.pageSize)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// tree walks the branch and leaf pages below the page id, and the buckets
+// they hold
+func (c *pageChecker) tree(id uint64) error {
+	p, err := c.page(id)
+	if err != nil || p == nil {
+		return err
+	}
+	flags := pageFlags(p)
+	if flags != branchPageFlag && flags != leafPageFlag {
+		c.report.add(fmt.Errorf("page %d: invalid type: %#x", id, flags))
+		return nil
+	}
+	refs, ok := c.elements(id, p, flags == leafPageFlag)
+	if !ok {
+		return nil
+	}
+	if flags == branchPageFlag && len(refs) == 0 {
+		c.report.add(fmt.Errorf("page %d: empty branch page", id))
+	}
+	for _, ref := range refs {
+		if err := c.tree(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elements checks that the elements of a branch or leaf page lie within the
+// page. It returns the child pages of a branch page, or the root pages of
+// the buckets of a leaf page, whose inline buckets are checked in place.
+func (c *pageChecker) elements(id uint64, p []byte, leaf bool) ([]uint64, bool) {
+	count := int(binary.NativeEndian.Uint16(p[10:]))
+	if pageHeaderSize+count*pageElementSize > len(p) {
+		c.report.add(fmt.Errorf("page %d: %d elements do not fit in the page", id, count))
+		return nil, false
+	}
+
+	var refs []uint64
+	for i := 0; i < count; i++ {
+		offset := pageHeaderSize + i*pageElementSize
+		elem := p[offset : offset+pageElementSize]
+		if !leaf {
+			pos, ksize := binary.NativeEndian.Uint32(elem), binary.NativeEndian.Uint32(elem[4:])
+			if uint64(offset)+uint64(pos)+uint64(ksize) > uint64(len(p)) {
+				c.report.add(fmt.Errorf("page %d: key of element %d out of bounds", id, i))
+				return nil, false
+			}
+			refs = append(refs, binary.NativeEndian.Uint64(elem[8:]))
+			continue
+		}
+
+		flags, pos := binary.NativeEndian.Uint32(elem), binary.NativeEndian.Uint32(elem[4:])
+		ksize, vsize := binary.NativeEndian.Uint32(elem[8:]), binary.NativeEndian.Uint32(elem[12:])
+		start := uint64(offset) + uint64(pos) + uint64(ksize)
+		end := start + uint64(vsize)
+		if end > uint64(len(p)) {
+			c.report.add(fmt.Errorf("page %d: element %d out of bounds", id, i))
+			return nil, false
+		}
+		if flags&bucketLeafFlag == 0 {
+			continue
+		}
+		value := p[start:end]
+		if len(value) < bucketHeaderSize {
+			c.report.add(fmt.Errorf("page %d: bucket header of element %d too short", id, i))
+			return nil, false
+		}
+		if root := binary.NativeEndian.Uint64(value); root != 0 {
+			refs = append(refs, root)
+			continue
+		}
+		// Inline buckets hold a leaf page in their value
+		inline := value[bucketHeaderSize:]
+		if len(inline) < pageHeaderSize || pageFlags(inline) != leafPageFlag {
+			c.report.add(fmt.Errorf("page %d: invalid inline bucket in element %d", id, i))
+			return nil, false
+		}
+		nested, ok := c.elements(id, inline, true)
+		if !ok {
+			return nil, false
+		}
+		refs = append(refs, nested...)
+	}
+	return refs, true
+}
+
+// pageFlags returns the type flags of a page
+func pageFlags(p []byte) uint16 {
+	return binary.NativeEndian.Uint16(p[8:])
+}
@@ -0,0 +1,233 @@
+package bolt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// CompactOptions configures Compact
+type CompactOptions struct {
+	// BatchSize is the number of keys and buckets written per transaction,
+	// 10000 when zero. Smaller batches use less memory.
+	BatchSize int
+	// FillPercent is how full pages are packed, from 0.1 to 1, bolt's
+	// default of 0.5 when zero. Files that are mostly read compact best with
+	// 1, while files that keep growing in the middle of buckets split fewer
+	// pages with the default.
+	FillPercent float64
+	// Progress is called after each transaction with the number of keys and
+	// buckets copied so far, and the bytes of keys and values they hold
+	Progress func(items int, bytes int64)
+}
+
+// CompactResult describes a finished compaction
+type CompactResult struct {
+	Items      int   // Keys and buckets copied
+	Bytes      int64 // Bytes of keys and values copied
+	SourceSize int64 // Size of the file before compaction
+	Size       int64 // Size of the compacted file
+}
+
+// String describes the sizes before and after the compaction
+func (r CompactResult) String() string {
+	saved := 0.0
+	if r.SourceSize > 0 {
+		saved = 100 * float64(r.SourceSize-r.Size) / float64(r.SourceSize)
+	}
+	return fmt.Sprintf("%s → %s (%.1f%% smaller)", FormatSize(int(r.SourceSize)), FormatSize(int(r.Size)), saved)
+}
+
+// Compact copies every bucket, nested bucket and key of the database into a
+// new file at dst, leaving out the free pages that make files grow but never
+// shrink. The database is read in a single read transaction, while the copy
+// is written in batches. dst must not exist.
+func (b *DB) Compact(dst string, opts CompactOptions) (CompactResult, error) {
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 10000
+	}
+	if opts.FillPercent == 0 {
+		opts.FillPercent = bolt.DefaultFillPercent
+	}
+	if opts.BatchSize < 0 {
+		return CompactResult{}, fmt.Errorf("batch size must be positive, got %d", opts.BatchSize)
+	}
+	if opts.FillPercent < 0.1 || opts.FillPercent > 1 {
+		return CompactResult{}, fmt.Errorf("fill percent must be between 0.1 and 1, got %g", opts.FillPercent)
+	}
+	if same, _ := sameFile(b.Path, dst); same {
+		return CompactResult{}, fmt.Errorf("cannot compact %s into itself", b.Path)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return CompactResult{}, fmt.Errorf("%s already exists", dst)
+	}
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	out, err := bolt.Open(dst, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("could not create %s: %v", dst, err)
+	}
+	c := &compactor{dst: out, opts: opts, result: CompactResult{SourceSize: info.Size()}}
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return c.copyBucket(nil, name, bucket)
+		})
+	})
+	if err == nil {
+		err = c.commit()
+	} else if c.tx != nil {
+		c.tx.Rollback()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return CompactResult{}, fmt.Errorf("could not compact into %s: %v", dst, err)
+	}
+
+	if info, err = os.Stat(dst); err != nil {
+		return CompactResult{}, err
+	}
+	c.result.Size = info.Size()
+	return c.result, nil
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return filepath.Clean(a) == filepath.Clean(b), nil
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+// compactor writes the copy of a database in batches
+type compactor struct {
+	dst     *bolt.DB
+	opts    CompactOptions
+	tx      *bolt.Tx
+	pending int // Items written in the current transaction
+	result  CompactResult
+}
+
+// copyBucket copies a bucket, with its nested buckets and sequence, into the
+// bucket at parent
+func (c *compactor) copyBucket(parent []string, name []byte, src *bolt.Bucket) error {
+	if err := c.write(parent, name, nil); err != nil {
+		return err
+	}
+	path := append(append([]string{}, parent...), string(name))
+
+	cur := src.Cursor()
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		var err error
+		if v == nil {
+			err = c.copyBucket(path, k, src.Bucket(k))
+		} else {
+			err = c.write(path, k, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if seq := src.Sequence(); seq != 0 {
+		bucket, err := c.bucket(path)
+		if err != nil {
+			return err
+		}
+		return bucket.SetSequence(seq)
+	}
+	return nil
+}
+
+// write puts a key, or creates a bucket when v is nil, in the bucket at
+// path, starting a new transaction when the current one is full
+func (c *compactor) write(path []string, k, v []byte) error {
+	if c.tx != nil && c.pending >= c.opts.BatchSize {
+		if err := c.commit(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if len(path) == 0 {
+		if c.tx == nil {
+			if c.tx, err = c.dst.Begin(true); err != nil {
+				return err
+			}
+		}
+		var bucket *bolt.Bucket
+		bucket, err = c.tx.CreateBucket(k)
+		if err == nil {
+			bucket.FillPercent = c.opts.FillPercent
+		}
+	} else {
+		var parent *bolt.Bucket
+		if parent, err = c.bucket(path); err != nil {
+			return err
+		}
+		if v == nil {
+			var bucket *bolt.Bucket
+			bucket, err = parent.CreateBucket(k)
+			if err == nil {
+				bucket.FillPercent = c.opts.FillPercent
+			}
+		} else {
+			err = parent.Put(k, v)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	c.pending++
+	c.result.Items++
+	c.result.Bytes += int64(len(k) + len(v))
+	return nil
+}
+
+// bucket returns the bucket at path in the current transaction, starting one
+// if needed
+func (c *compactor) bucket(path []string) (*bolt.Bucket, error) {
+	if c.tx == nil {
+		var err error
+		if c.tx, err = c.dst.Begin(true); err != nil {
+			return nil, err
+		}
+	}
+	bucket, err := bucketAt(c.tx, path)
+	if err != nil {
+		return nil, err
+	}
+	bucket.FillPercent = c.opts.FillPercent
+	return bucket, nil
+}
+
+// commit commits the current transaction and reports progress
+func (c *compactor) commit() error {
+	if c.tx == nil {
+		return nil
+	}
+	err := c.tx.Commit()
+	c.tx = nil
+	c.pending = 0
+	if err != nil {
+		return err
+	}
+	if c.opts.Progress != nil {
+		c.opts.Progress(c.result.Items, c.result.Bytes)
+	}
+	return nil
+}
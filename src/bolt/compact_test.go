@@ -0,0 +1,128 @@
+package bolt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openCompactDB returns a database with nested buckets, a sequence and free
+// pages left by deleted keys
+func openCompactDB(t *testing.T) *DB {
+	t.Helper()
+	db := openTestDB(t, "k1", "k2")
+	nested := &Tree{Sequence: 7, Entries: []TreeEntry{
+		{Key: []byte("x"), Value: []byte("1")},
+		{Key: []byte("deeper"), Bucket: &Tree{Entries: []TreeEntry{{Key: []byte("y"), Value: []byte{}}}}},
+	}}
+	if err := db.WriteTree([]string{"b", "nested"}, nested); err != nil {
+		t.Fatal(err)
+	}
+	large := &Tree{}
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key%04d", i)
+		large.Entries = append(large.Entries, TreeEntry{Key: []byte(key), Value: []byte(strings.Repeat("v", 200))})
+	}
+	if err := db.WriteTree([]string{"large"}, large); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2000; i += 2 {
+		if err := db.DeleteValue([]string{"large"}, []byte(fmt.Sprintf("key%04d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestCompact(t *testing.T) {
+	db := openCompactDB(t)
+	want := map[string]*Tree{}
+	for _, name := range []string{"b", "large"} {
+		tree, err := db.ReadTree([]string{name}, 1<<30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[name] = tree
+	}
+
+	tests := []struct {
+		name         string
+		opts         CompactOptions
+		wantProgress int // Calls of Progress
+	}{
+		{name: "default batch", wantProgress: 1},
+		{name: "small batches", opts: CompactOptions{BatchSize: 300}, wantProgress: 4},
+		{name: "one item per batch", opts: CompactOptions{BatchSize: 1, FillPercent: 1}, wantProgress: 1008},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := 0
+			tt.opts.Progress = func(items int, bytes int64) { progress++ }
+			dst := filepath.Join(t.TempDir(), "compact.db")
+			result, err := db.Compact(dst, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Items != 1008 || progress != tt.wantProgress {
+				t.Errorf("copied %d items in %d batches, want 1008 in %d", result.Items, progress, tt.wantProgress)
+			}
+			if result.Size >= result.SourceSize {
+				t.Errorf("compacted to %d bytes from %d", result.Size, result.SourceSize)
+			}
+
+			out := &DB{Path: dst, ReadOnly: true}
+			if err := out.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			for name, tree := range want {
+				got, err := out.ReadTree([]string{name}, 1<<30)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.equal(tree) {
+					t.Errorf("bucket %s differs after compaction", name)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactRefuses(t *testing.T) {
+	db := openCompactDB(t)
+	existing := filepath.Join(t.TempDir(), "existing.db")
+	if err := os.WriteFile(existing, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dst     string
+		opts    CompactOptions
+		wantErr string
+	}{
+		{name: "itself", dst: db.Path, wantErr: "into itself"},
+		{name: "existing file", dst: existing, wantErr: "already exists"},
+		{name: "negative batch", opts: CompactOptions{BatchSize: -1}, wantErr: "batch size"},
+		{name: "fill percent", opts: CompactOptions{FillPercent: 2}, wantErr: "fill percent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := tt.dst
+			if dst == "" {
+				dst = filepath.Join(t.TempDir(), "compact.db")
+			}
+			_, err := db.Compact(dst, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+			if tt.dst == "" {
+				if _, err := os.Stat(dst); err == nil {
+					t.Error("a refused compaction created the file")
+				}
+			}
+		})
+	}
+}
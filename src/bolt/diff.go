@@ -0,0 +1,97 @@
+package bolt
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+)
+
+// ChangeKind is how an item differs between two databases
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+// Change is a bucket or key that differs between two databases. Added and
+// removed buckets are reported without their contents.
+type Change struct {
+	Kind     ChangeKind
+	Path     []string // Path of the bucket holding the item
+	Key      []byte
+	IsBucket bool
+	Old, New []byte // Values in the first and second database, nil if absent
+}
+
+// Diff walks two databases in key order with a cursor on each, and calls fn
+// for every bucket and key that was added, removed or changed going from a
+// to b. A key that became a bucket, or the other way round, is reported as
+// removed and added. Diff stops at the first error returned by fn.
+func Diff(a, b *DB, fn func(Change) error) error {
+	return a.db.View(func(ta *bolt.Tx) error {
+		return b.db.View(func(tb *bolt.Tx) error {
+			d := differ{fn: fn}
+			return d.walk(nil, ta.Cursor(), tb.Cursor(),
+				func(k []byte) *bolt.Bucket { return ta.Bucket(k) },
+				func(k []byte) *bolt.Bucket { return tb.Bucket(k) })
+		})
+	})
+}
+
+// differ compares the buckets of two databases
+type differ struct {
+	fn func(Change) error
+}
+
+// walk compares the items under two cursors. child opens the nested bucket
+// named by a key on each side.
+func (d *differ) walk(path []string, ca, cb *bolt.Cursor, childA, childB func([]byte) *bolt.Bucket) error {
+	ka, va := ca.First()
+	kb, vb := cb.First()
+	for ka != nil || kb != nil {
+		cmp := bytes.Compare(ka, kb)
+		switch {
+		case kb == nil:
+			cmp = -1
+		case ka == nil:
+			cmp = 1
+		}
+
+		var err error
+		switch {
+		case cmp < 0:
+			err = d.report(Removed, path, ka, va, nil)
+			ka, va = ca.Next()
+		case cmp > 0:
+			err = d.report(Added, path, kb, nil, vb)
+			kb, vb = cb.Next()
+		default:
+			switch {
+			case va == nil && vb == nil:
+				a, b := childA(ka), childB(kb)
+				child := append(path[:len(path):len(path)], string(ka))
+				err = d.walk(child, a.Cursor(), b.Cursor(), a.Bucket, b.Bucket)
+			case va == nil || vb == nil:
+				if err = d.report(Removed, path, ka, va, nil); err == nil {
+					err = d.report(Added, path, kb, nil, vb)
+				}
+			case !bytes.Equal(va, vb):
+				err = d.fn(Change{Kind: Changed, Path: path, Key: ka, Old: va, New: vb})
+			}
+			ka, va = ca.Next()
+			kb, vb = cb.Next()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// report passes an added or removed item to fn, with nil values for buckets
+func (d *differ) report(kind ChangeKind, path []string, k, old, new []byte) error {
+	isBucket := old == nil && new == nil
+	return d.fn(Change{Kind: kind, Path: path, Key: k, IsBucket: isBucket, Old: old, New: new})
+}
@@ -0,0 +1,8 @@
+package bolt
+
+// LockHolder is a process holding a lock on a database file
+type LockHolder struct {
+	PID     int
+	Command string // Command line of the process, empty when unknown
+	Write   bool   // Whether the lock is exclusive, as taken by writers
+}
@@ -0,0 +1,67 @@
+package bolt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// LockHolders returns the processes holding a lock on the file at path, as
+// listed in /proc/locks. Processes in other PID namespaces may be missing.
+func LockHolders(path string) ([]LockHolder, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, nil
+	}
+	// /proc/locks identifies files as major:minor:inode, with the device
+	// numbers in hex
+	file := fmt.Sprintf("%02x:%02x:%d", unix.Major(uint64(st.Dev)), unix.Minor(uint64(st.Dev)), st.Ino)
+
+	f, err := os.Open("/proc/locks")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var holders []LockHolder
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// e.g. "1: FLOCK  ADVISORY  WRITE 1234 fd:01:393219 0 EOF"; blocked
+		// waiters are listed with "->" after the id and are skipped
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[1] == "->" || fields[5] != file {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			continue
+		}
+		holders = append(holders, LockHolder{
+			PID:     pid,
+			Command: processCommand(pid),
+			Write:   fields[3] == "WRITE",
+		})
+	}
+	return holders, scanner.Err()
+}
+
+// processCommand returns the command line of a process, or its name when the
+// command line cannot be read
+func processCommand(pid int) string {
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil && len(cmdline) > 0 {
+		return strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return strings.TrimSpace(string(comm))
+	}
+	return ""
+}
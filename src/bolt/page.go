@@ -0,0 +1,35 @@
+package bolt
+
+// Entry is a single item read from a bucket: either a key-value pair or,
+// when IsBucket is set, a nested bucket with a nil Value
+type Entry struct {
+	Key      []byte
+	Value    []byte
+	IsBucket bool
+}
+
+// Page is a window of entries read from a bucket in key order
+type Page struct {
+	Entries []Entry
+	HasPrev bool // There are entries before the first one of the page
+	HasNext bool // There are entries after the last one of the page
+}
+
+// After returns the smallest key that sorts strictly after key, which can be
+// used as the start of the following page
+func After(key []byte) []byte {
+	next := make([]byte, len(key)+1)
+	copy(next, key)
+	return next
+}
+
+// newEntry copies a key-value pair out of a transaction
+func newEntry(k, v []byte) Entry {
+	e := Entry{Key: append([]byte{}, k...)}
+	if v == nil {
+		e.IsBucket = true
+	} else {
+		e.Value = append([]byte{}, v...)
+	}
+	return e
+}
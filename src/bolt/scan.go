@@ -0,0 +1,161 @@
+package bolt
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+)
+
+// Range limits a scan to keys starting with Prefix and within [From, To).
+// Nil fields do not constrain the scan, so the zero Range matches every key.
+type Range struct {
+	Prefix []byte
+	From   []byte
+	To     []byte
+}
+
+// IsZero reports whether the range matches every key
+func (r Range) IsZero() bool {
+	return r.Prefix == nil && r.From == nil && r.To == nil
+}
+
+// Contains reports whether key falls within the range
+func (r Range) Contains(key []byte) bool {
+	if r.Prefix != nil && !bytes.HasPrefix(key, r.Prefix) {
+		return false
+	}
+	if r.From != nil && bytes.Compare(key, r.From) < 0 {
+		return false
+	}
+	if r.To != nil && bytes.Compare(key, r.To) >= 0 {
+		return false
+	}
+	return true
+}
+
+// lower returns the smallest key the range can contain, or nil for no bound
+func (r Range) lower() []byte {
+	if r.From != nil && bytes.Compare(r.From, r.Prefix) > 0 {
+		return r.From
+	}
+	return r.Prefix
+}
+
+// upper returns the exclusive upper bound of the range, or nil for no bound
+func (r Range) upper() []byte {
+	end := prefixEnd(r.Prefix)
+	if r.To != nil && (end == nil || bytes.Compare(r.To, end) < 0) {
+		return r.To
+	}
+	return end
+}
+
+// prefixEnd returns the first key that sorts after every key with prefix,
+// or nil when there is none
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// maxKey returns the larger of two keys, treating nil as unbounded below
+func maxKey(a, b []byte) []byte {
+	if a == nil || (b != nil && bytes.Compare(b, a) > 0) {
+		return b
+	}
+	return a
+}
+
+// minKey returns the smaller of two keys, treating nil as unbounded above
+func minKey(a, b []byte) []byte {
+	if a == nil || (b != nil && bytes.Compare(b, a) < 0) {
+		return b
+	}
+	return a
+}
+
+// seekBefore positions the cursor on the last key strictly before bound, or
+// on the last key of the bucket when bound is nil
+func seekBefore(c *bolt.Cursor, bound []byte) ([]byte, []byte) {
+	if bound == nil {
+		return c.Last()
+	}
+	if k, _ := c.Seek(bound); k == nil {
+		return c.Last()
+	}
+	return c.Prev()
+}
+
+// Scan returns up to limit entries of the bucket at path that fall within r,
+// with keys at or after from, or from the start of the range when from is nil.
+// Keys are located with Cursor.Seek so the scan never walks keys outside r.
+func (b *DB) Scan(path []string, r Range, from []byte, limit int) (Page, error) {
+	var page Page
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+
+		start := maxKey(r.lower(), from)
+		c := bucket.Cursor()
+		var k, v []byte
+		if start == nil {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(start)
+		}
+		for ; k != nil && r.Contains(k) && len(page.Entries) < limit; k, v = c.Next() {
+			page.Entries = append(page.Entries, newEntry(k, v))
+		}
+		page.HasNext = k != nil && r.Contains(k)
+
+		// Look behind the starting point with a second cursor
+		if start != nil {
+			k, _ := seekBefore(bucket.Cursor(), start)
+			page.HasPrev = k != nil && r.Contains(k)
+		}
+		return nil
+	})
+	return page, err
+}
+
+// ScanBackward returns up to limit entries of the bucket at path that fall
+// within r, with keys strictly before to, or the last entries of the range
+// when to is nil. The entries are returned in ascending key order.
+func (b *DB) ScanBackward(path []string, r Range, to []byte, limit int) (Page, error) {
+	var page Page
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+
+		end := minKey(r.upper(), to)
+		if end != nil {
+			k, _ := bucket.Cursor().Seek(end)
+			page.HasNext = k != nil && r.Contains(k)
+		}
+
+		c := bucket.Cursor()
+		var entries []Entry
+		k, v := seekBefore(c, end)
+		for ; k != nil && r.Contains(k) && len(entries) < limit; k, v = c.Prev() {
+			entries = append(entries, newEntry(k, v))
+		}
+		page.HasPrev = k != nil && r.Contains(k)
+
+		// Reverse into key order
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+		page.Entries = entries
+		return nil
+	})
+	return page, err
+}
@@ -0,0 +1,96 @@
+package bolt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB returns a database with a bucket "b" holding keys, each with
+// itself as value
+func openTestDB(t *testing.T, keys ...string) *DB {
+	t.Helper()
+	db := &DB{Path: filepath.Join(t.TempDir(), "test.db")}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.CreateBucket([]string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if err := db.PutValue([]string{"b"}, []byte(k), []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// pageString describes a page as its keys, with "<" and ">" marking that
+// there are entries before and after it
+func pageString(p Page) string {
+	var keys []string
+	if p.HasPrev {
+		keys = append(keys, "<")
+	}
+	for _, e := range p.Entries {
+		keys = append(keys, string(e.Key))
+	}
+	if p.HasNext {
+		keys = append(keys, ">")
+	}
+	return strings.Join(keys, " ")
+}
+
+func TestScan(t *testing.T) {
+	db := openTestDB(t, "a1", "a2", "a3", "b1", "b2", "c1", "c\xff")
+
+	tests := []struct {
+		name     string
+		r        Range
+		from     []byte // Start of a forward scan
+		to       []byte // End of a backward scan
+		limit    int
+		backward bool
+		want     string
+	}{
+		{name: "first page", limit: 3, want: "a1 a2 a3 >"},
+		{name: "next page", from: After([]byte("a3")), limit: 3, want: "< b1 b2 c1 >"},
+		{name: "last page", from: []byte("c1"), limit: 3, want: "< c1 c\xff"},
+		{name: "prefix", r: Range{Prefix: []byte("b")}, limit: 5, want: "b1 b2"},
+		{name: "prefix paged", r: Range{Prefix: []byte("a")}, from: []byte("a2"), limit: 1, want: "< a2 >"},
+		{name: "prefix ending in 0xff", r: Range{Prefix: []byte("c\xff")}, limit: 5, want: "c\xff"},
+		{name: "from and to", r: Range{From: []byte("a2"), To: []byte("b2")}, limit: 5, want: "a2 a3 b1"},
+		{name: "from before the range", r: Range{From: []byte("b")}, from: []byte("a"), limit: 1, want: "b1 >"},
+		{name: "empty range", r: Range{Prefix: []byte("d")}, limit: 5, want: ""},
+		{name: "backward last page", limit: 3, backward: true, want: "< b2 c1 c\xff"},
+		{name: "backward previous page", to: []byte("b2"), limit: 3, backward: true, want: "< a2 a3 b1 >"},
+		{name: "backward first page", to: []byte("a3"), limit: 3, backward: true, want: "a1 a2 >"},
+		{name: "backward prefix", r: Range{Prefix: []byte("a")}, limit: 2, backward: true, want: "< a2 a3"},
+		{name: "backward to", r: Range{To: []byte("b")}, limit: 5, backward: true, want: "a1 a2 a3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page Page
+			var err error
+			if tt.backward {
+				page, err = db.ScanBackward([]string{"b"}, tt.r, tt.to, tt.limit)
+			} else {
+				page, err = db.Scan([]string{"b"}, tt.r, tt.from, tt.limit)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pageString(page); got != tt.want {
+				t.Errorf("page = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanMissingBucket(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Scan([]string{"missing"}, Range{}, nil, 10); err == nil {
+		t.Error("scan of a missing bucket succeeded")
+	}
+}
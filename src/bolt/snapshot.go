@@ -0,0 +1,138 @@
+package bolt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrLocked is returned by Open when another process holds the lock of the
+// database for longer than the timeout
+var ErrLocked = errors.New("database is locked by another process")
+
+// snapshotAttempts is how many times a locked file is copied before giving
+// up on getting a copy during which it did not change
+const snapshotAttempts = 3
+
+// Snapshot is a copy of a database taken to browse it while it is locked
+type Snapshot struct {
+	Source     string    // Path of the database the snapshot was taken of
+	Path       string    // Path of the copy
+	Taken      time.Time // When the copy was made
+	Consistent bool      // Whether the copy is known to be a consistent state
+	Warning    string    // Why the copy may not be consistent
+}
+
+// TakeSnapshot copies the database at path to a temp file. When the database
+// can be opened read-only the copy is made within a read transaction and is
+// consistent. When a writer holds the lock the file is copied as is, which is
+// only consistent if nothing was committed meanwhile: the copy is retried
+// while the file changes, checked, and flagged with a warning when it may
+// still be torn.
+func TakeSnapshot(path string) (*Snapshot, error) {
+	f, err := os.CreateTemp("", "bolt-tui-snapshot-*.db")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	s := &Snapshot{Source: path, Path: f.Name(), Taken: time.Now()}
+
+	err = copyLocked(path, s.Path)
+	if err == nil {
+		s.Consistent = true
+		return s, nil
+	}
+	if !errors.Is(err, bolt.ErrTimeout) {
+		s.Remove()
+		return nil, fmt.Errorf("could not snapshot db: %v", err)
+	}
+
+	stable := false
+	for i := 0; i < snapshotAttempts && !stable; i++ {
+		s.Taken = time.Now()
+		stable, err = copyFile(path, s.Path)
+		if err != nil {
+			s.Remove()
+			return nil, fmt.Errorf("could not snapshot db: %v", err)
+		}
+	}
+	if err := checkFile(s.Path); err != nil {
+		s.Warning = fmt.Sprintf("the copy is damaged, it was probably taken during a write: %v", err)
+	} else if !stable {
+		s.Warning = "the database changed while it was copied, the copy may be inconsistent"
+	} else {
+		s.Warning = "the database was copied while locked by a writer, the copy may be inconsistent"
+	}
+	return s, nil
+}
+
+// copyLocked copies the database within a read transaction, which needs a
+// shared lock on the file
+func copyLocked(src, dst string) error {
+	db, err := bolt.Open(src, 0600, &bolt.Options{Timeout: 100 * time.Millisecond, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(dst, 0600)
+	})
+}
+
+// copyFile copies the file at src to dst and reports whether its size and
+// modification time stayed the same while it was copied
+func copyFile(src, dst string) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	before, err := in.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	after, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	return before.Size() == after.Size() && before.ModTime().Equal(after.ModTime()), nil
+}
+
+// checkFile opens a database and verifies its pages
+func checkFile(path string) error {
+	db := &DB{Path: path, Timeout: 1 * time.Second}
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := db.Check()
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		return report.Problems[0]
+	}
+	return nil
+}
+
+// Remove deletes the copy
+func (s *Snapshot) Remove() error {
+	return os.Remove(s.Path)
+}
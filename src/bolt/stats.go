@@ -0,0 +1,141 @@
+package bolt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/boltdb/bolt"
+)
+
+// Stats describes how the space of a database file is used
+type Stats struct {
+	FileSize      int64
+	PageSize      int
+	FreePages     int // Pages on the freelist, reusable by later writes
+	PendingPages  int // Pages freed by transactions that readers may still see
+	FreeBytes     int // Bytes in free pages
+	FreelistBytes int // Bytes used by the freelist itself
+	Buckets       []BucketStats
+}
+
+// BucketStats describes a bucket. Counts and sizes include its nested
+// buckets, as its pages hold theirs.
+type BucketStats struct {
+	Path    []string
+	Keys    int
+	Buckets int // Nested buckets
+	Depth   int // Levels of the B+tree
+	Pages   int // Branch, leaf and overflow pages
+	Alloc   int // Bytes allocated in pages, zero for buckets stored inline in their parent's page
+	InUse   int // Bytes actually used
+}
+
+// Fill returns the share of the allocated bytes in use, from 0 to 1
+func (s BucketStats) Fill() float64 {
+	if s.Alloc == 0 {
+		return 0
+	}
+	return float64(s.InUse) / float64(s.Alloc)
+}
+
+// Stats gathers the statistics of the database and of every bucket, nested
+// buckets included, sorted by allocated size with the largest first
+func (b *DB) Stats() (Stats, error) {
+	dbStats := b.db.Stats()
+	stats := Stats{
+		PageSize:      b.db.Info().PageSize,
+		FreePages:     dbStats.FreePageN,
+		PendingPages:  dbStats.PendingPageN,
+		FreeBytes:     dbStats.FreeAlloc,
+		FreelistBytes: dbStats.FreelistInuse,
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		stats.FileSize = tx.Size()
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			bucketStats([]string{string(name)}, bucket, &stats.Buckets)
+			return nil
+		})
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	sort.SliceStable(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Alloc > stats.Buckets[j].Alloc
+	})
+	return stats, nil
+}
+
+// bucketStats appends the statistics of a bucket and its nested buckets
+func bucketStats(path []string, bucket *bolt.Bucket, out *[]BucketStats) {
+	*out = append(*out, statsOf(path, bucket))
+
+	c := bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil {
+			child := append(append([]string{}, path...), string(k))
+			bucketStats(child, bucket.Bucket(k), out)
+		}
+	}
+}
+
+// statsOf returns the statistics of a bucket
+func statsOf(path []string, bucket *bolt.Bucket) BucketStats {
+	s := bucket.Stats()
+	bs := BucketStats{
+		Path:    path,
+		Keys:    s.KeyN,
+		Buckets: s.BucketN - 1,
+		Depth:   s.Depth,
+		Pages:   s.BranchPageN + s.BranchOverflowN + s.LeafPageN + s.LeafOverflowN,
+		Alloc:   s.BranchAlloc + s.LeafAlloc,
+		InUse:   s.BranchInuse + s.LeafInuse,
+	}
+	if bs.Alloc == 0 {
+		bs.InUse = s.InlineBucketInuse
+	}
+	return bs
+}
+
+// String renders the statistics as a report with a table of buckets
+func (s Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "File size:  %s (%d pages of %s)\n", FormatSize(int(s.FileSize)), int(s.FileSize)/max(s.PageSize, 1), FormatSize(s.PageSize))
+	fmt.Fprintf(&b, "Free pages: %d free, %d pending (%s reusable, freelist %s)\n\n",
+		s.FreePages, s.PendingPages, FormatSize(s.FreeBytes), FormatSize(s.FreelistBytes))
+
+	if len(s.Buckets) == 0 {
+		b.WriteString("No buckets.\n")
+		return b.String()
+	}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "SIZE\tIN USE\tFILL\tPAGES\tDEPTH\tKEYS\tBUCKETS\t\tBUCKET")
+	for _, bs := range s.Buckets {
+		size, fill := FormatSize(bs.Alloc), fmt.Sprintf("%.0f%%", bs.Fill()*100)
+		if bs.Alloc == 0 {
+			size, fill = "inline", "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t\t%s\n",
+			size, FormatSize(bs.InUse), fill, bs.Pages, bs.Depth, bs.Keys, bs.Buckets, PathString(bs.Path))
+	}
+	w.Flush()
+	b.WriteString("\nSizes and counts of a bucket include its nested buckets.\n")
+	return b.String()
+}
+
+// FormatSize formats a number of bytes with a binary unit
+func FormatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
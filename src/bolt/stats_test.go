@@ -0,0 +1,89 @@
+package bolt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	db := openTestDB(t, "k1", "k2")
+	nested := &Tree{Entries: []TreeEntry{{Key: []byte("x"), Value: []byte("1")}}}
+	if err := db.WriteTree([]string{"b", "nested"}, nested); err != nil {
+		t.Fatal(err)
+	}
+	large := &Tree{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key%04d", i)
+		large.Entries = append(large.Entries, TreeEntry{Key: []byte(key), Value: []byte(strings.Repeat("v", 100))})
+	}
+	if err := db.WriteTree([]string{"large"}, large); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.FileSize == 0 || stats.PageSize == 0 {
+		t.Errorf("file size %d, page size %d, want both set", stats.FileSize, stats.PageSize)
+	}
+
+	tests := []struct {
+		path    string
+		keys    int
+		buckets int
+		depth   int
+		inline  bool // Small buckets without nested ones are kept in their parent's page
+	}{
+		{path: "large", keys: 1000, depth: 2},
+		{path: "b", keys: 4, buckets: 1, depth: 2},
+		{path: "b/nested", keys: 1, depth: 1, inline: true},
+	}
+	if len(stats.Buckets) != len(tests) {
+		t.Fatalf("got %d buckets, want %d", len(stats.Buckets), len(tests))
+	}
+	// Buckets are sorted by allocated size, the largest first
+	for i, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			bs := stats.Buckets[i]
+			if got := PathString(bs.Path); got != tt.path {
+				t.Fatalf("bucket %d is %s, want %s", i, got, tt.path)
+			}
+			if bs.Keys != tt.keys || bs.Buckets != tt.buckets {
+				t.Errorf("%d keys and %d buckets, want %d and %d", bs.Keys, bs.Buckets, tt.keys, tt.buckets)
+			}
+			if (bs.Alloc == 0) != tt.inline {
+				t.Errorf("allocated %d bytes, want inline: %v", bs.Alloc, tt.inline)
+			}
+			if bs.Depth != tt.depth {
+				t.Errorf("depth %d, want %d", bs.Depth, tt.depth)
+			}
+			if bs.InUse == 0 || (!tt.inline && bs.InUse > bs.Alloc) {
+				t.Errorf("%d bytes in use of %d allocated", bs.InUse, bs.Alloc)
+			}
+			if fill := bs.Fill(); fill < 0 || fill > 1 {
+				t.Errorf("fill %f out of range", fill)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.n); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
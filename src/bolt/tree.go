@@ -0,0 +1,175 @@
+package bolt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrTooLarge is returned by ReadTree when a bucket holds more than the limit
+var ErrTooLarge = errors.New("bucket is too large to copy in memory")
+
+// Tree is a copy of the contents of a bucket, nested buckets included
+type Tree struct {
+	Sequence uint64
+	Entries  []TreeEntry
+}
+
+// TreeEntry is a key of a Tree, holding either a value or a nested bucket
+type TreeEntry struct {
+	Key    []byte
+	Value  []byte
+	Bucket *Tree // Set for nested buckets, when Value is nil
+}
+
+// ReadTree copies the contents of the bucket at path into memory. It
+// returns ErrTooLarge when its keys and values add up to more than limit
+// bytes.
+func (b *DB) ReadTree(path []string, limit int) (*Tree, error) {
+	var tree *Tree
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		size := 0
+		tree, err = readTree(bucket, &size, limit)
+		return err
+	})
+	return tree, err
+}
+
+// readTree copies a bucket, adding the bytes copied to size
+func readTree(bucket *bolt.Bucket, size *int, limit int) (*Tree, error) {
+	tree := &Tree{Sequence: bucket.Sequence()}
+	c := bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		*size += len(k) + len(v)
+		if *size > limit {
+			return nil, ErrTooLarge
+		}
+		e := TreeEntry{Key: append([]byte{}, k...)}
+		if v == nil {
+			child, err := readTree(bucket.Bucket(k), size, limit)
+			if err != nil {
+				return nil, err
+			}
+			e.Bucket = child
+		} else {
+			e.Value = append([]byte{}, v...)
+		}
+		tree.Entries = append(tree.Entries, e)
+	}
+	return tree, nil
+}
+
+// CompareAndDeleteBucket deletes the bucket at path only if its contents
+// are still those of tree. It returns ErrValueChanged otherwise.
+func (b *DB) CompareAndDeleteBucket(path []string, tree *Tree) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		// The current contents only need to be read up to the size of tree
+		size := 0
+		current, err := readTree(bucket, &size, tree.size())
+		if errors.Is(err, ErrTooLarge) || (err == nil && !current.equal(tree)) {
+			return ErrValueChanged
+		}
+		if err != nil {
+			return err
+		}
+		name := []byte(path[len(path)-1])
+		if len(path) == 1 {
+			return tx.DeleteBucket(name)
+		}
+		parent, _ := bucketAt(tx, path[:len(path)-1])
+		return parent.DeleteBucket(name)
+	})
+}
+
+// size returns the bytes of the keys and values of the tree, as counted by
+// ReadTree
+func (t *Tree) size() int {
+	size := 0
+	for _, e := range t.Entries {
+		size += len(e.Key) + len(e.Value)
+		if e.Bucket != nil {
+			size += e.Bucket.size()
+		}
+	}
+	return size
+}
+
+// equal reports whether two trees hold the same keys, values and sequences
+func (t *Tree) equal(other *Tree) bool {
+	if t.Sequence != other.Sequence || len(t.Entries) != len(other.Entries) {
+		return false
+	}
+	for i, e := range t.Entries {
+		o := other.Entries[i]
+		if !bytes.Equal(e.Key, o.Key) || (e.Bucket == nil) != (o.Bucket == nil) {
+			return false
+		}
+		if e.Bucket == nil && !bytes.Equal(e.Value, o.Value) {
+			return false
+		}
+		if e.Bucket != nil && !e.Bucket.equal(o.Bucket) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTree creates the bucket at path with the contents of tree. The
+// bucket must not exist, and its parent must.
+func (b *DB) WriteTree(path []string, tree *Tree) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		name := []byte(path[len(path)-1])
+		var (
+			bucket *bolt.Bucket
+			err    error
+		)
+		if len(path) == 1 {
+			bucket, err = tx.CreateBucket(name)
+		} else {
+			var parent *bolt.Bucket
+			if parent, err = bucketAt(tx, path[:len(path)-1]); err == nil {
+				bucket, err = parent.CreateBucket(name)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("could not create bucket %s: %v", PathString(path), err)
+		}
+		return writeTree(bucket, tree)
+	})
+}
+
+// writeTree fills an empty bucket with the contents of tree
+func writeTree(bucket *bolt.Bucket, tree *Tree) error {
+	if err := bucket.SetSequence(tree.Sequence); err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		if e.Bucket == nil {
+			if err := bucket.Put(e.Key, e.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		child, err := bucket.CreateBucket(e.Key)
+		if err != nil {
+			return err
+		}
+		if err := writeTree(child, e.Bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
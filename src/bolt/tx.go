@@ -0,0 +1,199 @@
+package bolt
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// Tx is a transaction of View or Update, with buckets addressed by path
+type Tx struct {
+	tx *bolt.Tx
+}
+
+// View runs fn in a read-only transaction
+func (b *DB) View(fn func(*Tx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx})
+	})
+}
+
+// Update runs fn in a read-write transaction, which is committed when fn
+// returns nil and rolled back otherwise
+func (b *DB) Update(fn func(*Tx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx})
+	})
+}
+
+// Begin starts a transaction that stays open until Commit or Rollback, for
+// changes made over several steps
+func (b *DB) Begin(writable bool) (*Tx, error) {
+	tx, err := b.db.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx}, nil
+}
+
+// Commit writes the changes of a transaction started with Begin
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback discards a transaction started with Begin
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Writable reports whether the transaction can make changes
+func (t *Tx) Writable() bool {
+	return t.tx.Writable()
+}
+
+// HasBucket reports whether the bucket at path exists
+func (t *Tx) HasBucket(path []string) bool {
+	_, err := bucketAt(t.tx, path)
+	return err == nil
+}
+
+// Get looks up key in the bucket at path. exists is false when the bucket or
+// the key does not exist, and isBucket is set when key is a nested bucket.
+// The value is only valid for the life of the transaction.
+func (t *Tx) Get(path []string, key []byte) (value []byte, exists, isBucket bool) {
+	bucket, err := bucketAt(t.tx, path)
+	if err != nil {
+		return nil, false, false
+	}
+	k, v := bucket.Cursor().Seek(key)
+	if !bytes.Equal(k, key) {
+		return nil, false, false
+	}
+	if v == nil && bucket.Bucket(key) != nil {
+		return nil, true, true
+	}
+	return v, true, false
+}
+
+// CreateBucketIfNotExists creates the bucket at path and any missing parents
+func (t *Tx) CreateBucketIfNotExists(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
+	bucket, err := t.tx.CreateBucketIfNotExists([]byte(path[0]))
+	for i := 1; err == nil && i < len(path); i++ {
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(path[i]))
+	}
+	if err != nil {
+		return fmt.Errorf("could not create bucket %s: %v", PathString(path), err)
+	}
+	return nil
+}
+
+// Put sets the value of key in the bucket at path
+func (t *Tx) Put(path []string, key, value []byte) error {
+	bucket, err := bucketAt(t.tx, path)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		// Bolt would read the value back as nil, which stands for buckets
+		value = []byte{}
+	}
+	return bucket.Put(key, value)
+}
+
+// Delete removes key from the bucket at path
+func (t *Tx) Delete(path []string, key []byte) error {
+	bucket, err := bucketAt(t.tx, path)
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(key)
+}
+
+// ForEach calls fn in key order for the entries of the bucket at path, or for
+// the top-level buckets when path is empty, whose key starts with prefix.
+// v is nil for nested buckets. ForEach stops at the first error from fn.
+func (t *Tx) ForEach(path []string, prefix []byte, fn func(k, v []byte) error) error {
+	var c *bolt.Cursor
+	if len(path) == 0 {
+		c = t.tx.Cursor()
+	} else {
+		bucket, err := bucketAt(t.tx, path)
+		if err != nil {
+			return err
+		}
+		c = bucket.Cursor()
+	}
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBucket deletes the bucket at path along with everything nested in it
+func (t *Tx) DeleteBucket(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
+	name := []byte(path[len(path)-1])
+	if len(path) == 1 {
+		return t.tx.DeleteBucket(name)
+	}
+	parent, err := bucketAt(t.tx, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	return parent.DeleteBucket(name)
+}
+
+// CopyBucket copies the bucket at src with everything nested in it to a new
+// bucket at dst. The parent of dst must exist.
+func (t *Tx) CopyBucket(src, dst []string) error {
+	if len(dst) == 0 {
+		return fmt.Errorf("bucket path cannot be empty")
+	}
+	from, err := bucketAt(t.tx, src)
+	if err != nil {
+		return err
+	}
+	name := []byte(dst[len(dst)-1])
+	var to *bolt.Bucket
+	if len(dst) == 1 {
+		to, err = t.tx.CreateBucket(name)
+	} else {
+		var parent *bolt.Bucket
+		if parent, err = bucketAt(t.tx, dst[:len(dst)-1]); err == nil {
+			to, err = parent.CreateBucket(name)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not create bucket %s: %v", PathString(dst), err)
+	}
+	if err := to.SetSequence(from.Sequence()); err != nil {
+		return err
+	}
+	return copyBucket(to, from)
+}
+
+// BucketStats returns the statistics of the bucket at path
+func (t *Tx) BucketStats(path []string) (BucketStats, error) {
+	bucket, err := bucketAt(t.tx, path)
+	if err != nil {
+		return BucketStats{}, err
+	}
+	return statsOf(path, bucket), nil
+}
+
+// Sequence returns the sequence number of the bucket at path
+func (t *Tx) Sequence(path []string) (uint64, error) {
+	bucket, err := bucketAt(t.tx, path)
+	if err != nil {
+		return 0, err
+	}
+	return bucket.Sequence(), nil
+}
@@ -0,0 +1,51 @@
+package bolt
+
+import (
+	"github.com/boltdb/bolt"
+)
+
+// WalkFunc is called by Walk for every item of the database. path is the
+// path of the bucket holding the item and v is nil when the item is a nested
+// bucket. The arguments are only valid for the duration of the call.
+type WalkFunc func(path []string, k, v []byte) error
+
+// Walk visits every bucket, nested bucket and key-value pair of the database
+// depth first in key order, within a single read transaction. Top-level
+// buckets are reported with an empty path. Walking stops at the first error
+// returned by fn, which is returned by Walk.
+func (b *DB) Walk(fn WalkFunc) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if err := fn(nil, name, nil); err != nil {
+				return err
+			}
+			return walkBucket(bucket, []string{string(name)}, fn)
+		})
+	})
+}
+
+// WalkBucket visits everything in the bucket at path like Walk, with paths
+// starting at the bucket
+func (b *DB) WalkBucket(path []string, fn WalkFunc) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket, err := bucketAt(tx, path)
+		if err != nil {
+			return err
+		}
+		return walkBucket(bucket, append([]string{}, path...), fn)
+	})
+}
+
+// walkBucket visits everything in bucket, recursing into nested buckets
+func walkBucket(bucket *bolt.Bucket, path []string, fn WalkFunc) error {
+	return bucket.ForEach(func(k, v []byte) error {
+		if err := fn(path, k, v); err != nil {
+			return err
+		}
+		if v == nil {
+			child := append(path[:len(path):len(path)], string(k))
+			return walkBucket(bucket.Bucket(k), child, fn)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the check command
+const (
+	checkCorrupt = 1 // The file has inconsistent pages
+	checkFailed  = 2 // The file could not be opened or checked
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check <file>",
+	Short: "Verify the pages of a BoltDB file",
+	Long: `Verify the pages of a BoltDB file: that every page is either free or used by
+exactly one bucket, and that bucket pages are valid.
+
+Exit codes:
+  0  the file is healthy
+  1  the file is corrupt
+  2  the file could not be opened, e.g. it is not a BoltDB file or is locked`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		db := &bolt.DB{Path: args[0], ReadOnly: true, Timeout: timeout}
+		if err := db.Open(); errors.Is(err, bolt.ErrCorrupt) {
+			return &exitError{checkCorrupt, err}
+		} else if err != nil {
+			return &exitError{checkFailed, err}
+		}
+		defer db.Close()
+
+		report, err := db.Check()
+		if err != nil {
+			return &exitError{checkFailed, fmt.Errorf("could not check %s: %v", args[0], err)}
+		}
+		out := cmd.OutOrStdout()
+		for _, problem := range report.Problems {
+			fmt.Fprintln(out, problem)
+		}
+		if more := report.Count - len(report.Problems); more > 0 {
+			fmt.Fprintf(out, "and %d more problems\n", more)
+		}
+		if !report.OK() {
+			return &exitError{checkCorrupt, fmt.Errorf("%s is corrupt: %d problems in %d pages", args[0], report.Count, report.Pages)}
+		}
+		fmt.Fprintf(out, "%s: ok, %d pages checked\n", args[0], report.Pages)
+		return nil
+	},
+}
+
+// exitError ends the program with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
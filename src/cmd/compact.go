@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/spf13/cobra"
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact <src> <dst>",
+	Short: "Copy a BoltDB file into a new, compacted file",
+	Long: `Copy every bucket, nested bucket and key of a BoltDB file into a new file, leaving
+out the free pages that make BoltDB files grow but never shrink. The source is
+only read; the destination must not exist.`,
+	Args:          cobra.ExactArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		batch, _ := cmd.Flags().GetInt("batch-size")
+		fill, _ := cmd.Flags().GetFloat64("fill-percent")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		db := &bolt.DB{Path: args[0], ReadOnly: true, Timeout: timeout}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		out := cmd.ErrOrStderr()
+		opts := bolt.CompactOptions{BatchSize: batch, FillPercent: fill}
+		reported := false
+		if !quiet {
+			opts.Progress = func(items int, bytes int64) {
+				reported = true
+				fmt.Fprintf(out, "\rcopied %d keys and buckets (%s)", items, bolt.FormatSize(int(bytes)))
+			}
+		}
+		result, err := db.Compact(args[1], opts)
+		if reported {
+			fmt.Fprintln(out)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s → %s: %s\n", args[0], args[1], result)
+		return nil
+	},
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lunargon/bolt-tui/src/app"
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/diff"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the diff command
+const (
+	diffFound  = 1 // The files differ
+	diffFailed = 2 // A file could not be opened or read
+)
+
+const (
+	diffContext = 2  // Unchanged lines printed around each changed line
+	diffRanges  = 16 // Differing byte ranges printed for a binary value
+	diffBytes   = 16 // Bytes printed for each side of a range
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a.db> <b.db>",
+	Short: "Compare two BoltDB files",
+	Long: `Compare two BoltDB files, such as copies of a database taken before and after a
+migration. Buckets and keys are listed as added (+), removed (-) or changed (~)
+going from the first file to the second. Changed text and JSON values are
+compared line by line, and other values byte by byte.
+
+Exit codes:
+  0  the files have the same contents
+  1  the files differ
+  2  a file could not be opened or read`,
+	Args:          cobra.ExactArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if tui, _ := cmd.Flags().GetBool("tui"); tui {
+			return runDiffView(cmd, args[0], args[1])
+		}
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		a := &bolt.DB{Path: args[0], ReadOnly: true, Timeout: timeout}
+		if err := a.Open(); err != nil {
+			return &exitError{diffFailed, err}
+		}
+		defer a.Close()
+		b := &bolt.DB{Path: args[1], ReadOnly: true, Timeout: timeout}
+		if err := b.Open(); err != nil {
+			return &exitError{diffFailed, err}
+		}
+		defer b.Close()
+
+		out := cmd.OutOrStdout()
+		var added, removed, changed int
+		err := bolt.Diff(a, b, func(c bolt.Change) error {
+			switch c.Kind {
+			case bolt.Added:
+				added++
+			case bolt.Removed:
+				removed++
+			default:
+				changed++
+			}
+			if !quiet {
+				printChange(out, c)
+			}
+			return nil
+		})
+		if err != nil {
+			return &exitError{diffFailed, fmt.Errorf("could not compare %s and %s: %v", args[0], args[1], err)}
+		}
+		if added+removed+changed > 0 {
+			return &exitError{diffFound, fmt.Errorf("%s and %s differ: %d added, %d removed, %d changed", args[0], args[1], added, removed, changed)}
+		}
+		return nil
+	},
+}
+
+// runDiffView shows the differences of two files side by side
+func runDiffView(cmd *cobra.Command, a, b string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	m, err := app.NewDiff(a, b, timeout)
+	if err != nil {
+		return &exitError{diffFailed, err}
+	}
+	defer m.Close()
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("error running program: %v", err)
+	}
+	return nil
+}
+
+// printChange prints a change, followed by the differences of the values
+// when a key changed
+func printChange(out io.Writer, c bolt.Change) {
+	what := "key"
+	if c.IsBucket {
+		what = "bucket"
+	}
+	name := diffName(append(c.Path[:len(c.Path):len(c.Path)], string(c.Key))...)
+	switch c.Kind {
+	case bolt.Added:
+		if c.IsBucket {
+			fmt.Fprintf(out, "+ %s %s\n", what, name)
+		} else {
+			fmt.Fprintf(out, "+ %s %s (%d bytes)\n", what, name, len(c.New))
+		}
+	case bolt.Removed:
+		if c.IsBucket {
+			fmt.Fprintf(out, "- %s %s\n", what, name)
+		} else {
+			fmt.Fprintf(out, "- %s %s (%d bytes)\n", what, name, len(c.Old))
+		}
+	default:
+		fmt.Fprintf(out, "~ %s %s (%d → %d bytes)\n", what, name, len(c.Old), len(c.New))
+		if codec.IsText(c.Old) && codec.IsText(c.New) {
+			printLines(out, diff.Lines(diff.Split(c.Old), diff.Split(c.New)))
+		} else {
+			printRanges(out, diff.Bytes(c.Old, c.New))
+		}
+	}
+}
+
+// printLines prints a line diff with -/+ markers, and the unchanged lines
+// close to the changes
+func printLines(out io.Writer, lines []diff.Line) {
+	show := diff.Context(lines, diffContext)
+	for i, line := range lines {
+		if !show[i] {
+			if i == 0 || show[i-1] {
+				fmt.Fprintln(out, "    …")
+			}
+			continue
+		}
+		switch line.Op {
+		case diff.Delete:
+			fmt.Fprintf(out, "    - %s\n", line.Text)
+		case diff.Insert:
+			fmt.Fprintf(out, "    + %s\n", line.Text)
+		default:
+			fmt.Fprintf(out, "      %s\n", line.Text)
+		}
+	}
+}
+
+// printRanges prints the bytes that differ at each offset, in hex
+func printRanges(out io.Writer, ranges []diff.Range) {
+	for i, r := range ranges {
+		if i == diffRanges {
+			fmt.Fprintf(out, "    and %d more differing ranges\n", len(ranges)-i)
+			return
+		}
+		fmt.Fprintf(out, "    @%08x - %s\n", r.Offset, hexBytes(r.Old))
+		fmt.Fprintf(out, "    @%08x + %s\n", r.Offset, hexBytes(r.New))
+	}
+}
+
+// hexBytes formats the start of b as hex
+func hexBytes(b []byte) string {
+	if len(b) == 0 {
+		return "(none)"
+	}
+	s := fmt.Sprintf("% x", b[:min(len(b), diffBytes)])
+	if len(b) > diffBytes {
+		s += fmt.Sprintf(" … (%d bytes)", len(b))
+	}
+	return s
+}
+
+// diffName joins a bucket path and key with "/", quoting the names that are
+// not printable or that contain a "/"
+func diffName(names ...string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if name == "" || strings.ContainsAny(name, "/\"\n\t\r") || !codec.IsText([]byte(name)) {
+			name = strconv.Quote(name)
+		}
+		parts[i] = name
+	}
+	return strings.Join(parts, "/")
+}
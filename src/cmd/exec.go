@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/shell"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <file> <script|->",
+	Short: "Run a script of shell commands on a BoltDB file in one transaction",
+	Long: `Run a script of shell commands on a BoltDB file, one per line, such as the same
+data fix applied to the databases of several environments. The script runs in a
+single transaction: either every command succeeds and all the changes are
+written, or the first failing command stops the script and nothing is written.
+
+Scripts use the commands and syntax of the shell, such as put, delete (rm),
+rename (mv), copy (cp), mkbucket and cd. Lines starting with # are comments.
+The changes are listed once the script ends. With --dry-run the script runs the
+same way, and its changes are listed and then discarded.`,
+	Example: `  # fix.txt
+  cd users
+  put alice '{"age": 31}'
+  rename bob robert
+  delete -r sessions
+
+  bolt-tui exec prod.db fix.txt --dry-run`,
+	Args:          cobra.ExactArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if readOnly, _ := cmd.Flags().GetBool("read-only"); readOnly {
+			// Even a dry run takes the writer lock to run the script
+			return fmt.Errorf("exec writes to the database, which --read-only does not allow")
+		}
+		var script io.Reader = cmd.InOrStdin()
+		name := "stdin"
+		if args[1] != "-" {
+			f, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			script, name = f, args[1]
+		}
+
+		// Bolt would create a missing file, which is never what a typo means
+		if _, err := os.Stat(args[0]); err != nil {
+			return fmt.Errorf("could not open db: %v", err)
+		}
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		db := &bolt.DB{Path: args[0], Timeout: timeout}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		out := cmd.OutOrStdout()
+		changes, err := shell.New(db, out).Exec(script, name, dryRun)
+		for _, c := range changes {
+			fmt.Fprintln(out, c)
+		}
+		switch {
+		case err != nil && len(changes) > 0:
+			return fmt.Errorf("%v\nrolled back, none of the %d changes above were written", err, len(changes))
+		case err != nil:
+			return fmt.Errorf("%v\nrolled back, nothing was written", err)
+		case dryRun:
+			fmt.Fprintf(out, "dry run: %d changes, nothing was written\n", len(changes))
+		default:
+			fmt.Fprintf(out, "%d changes written\n", len(changes))
+		}
+		return nil
+	},
+}
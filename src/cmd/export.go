@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/dump"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file> [bucket/path]",
+	Short: "Export a BoltDB file or bucket to JSON or JSON Lines",
+	Long: `Export the buckets, nested buckets and keys of a BoltDB file, or of one of its
+buckets, as JSON records. Text is written as strings, binary data in base64 or
+hex, and values can be written decoded with a codec; the export can be
+imported back with the import command.`,
+	Args:          cobra.RangeArgs(1, 2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appOpts, err := appOptions(cmd)
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		binary, _ := cmd.Flags().GetString("binary")
+		decode, _ := cmd.Flags().GetString("decode")
+
+		opts := dump.ExportOptions{Binary: binary}
+		if format == "" && strings.EqualFold(filepath.Ext(output), ".json") {
+			format = "json"
+		}
+		if format != "" {
+			if opts.Format, err = dump.ParseFormat(format); err != nil {
+				return err
+			}
+		}
+		var decoder codec.Codec
+		if decode != "" && decode != "auto" {
+			protos, err := loadProto(cmd)
+			if err != nil {
+				return err
+			}
+			if decoder, err = lookupCodec(protos, decode); err != nil {
+				return err
+			}
+		}
+		opts.Codec = func(path []string, value []byte) codec.Codec {
+			if c := appOpts.Codecs[bolt.PathString(path)]; c != nil {
+				return c
+			}
+			if decode == "auto" {
+				return codec.Detect(value)
+			}
+			return decoder
+		}
+
+		db := &bolt.DB{Path: args[0], ReadOnly: true, Timeout: appOpts.Timeout}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var path []string
+		if len(args) > 1 {
+			path = strings.Split(args[1], "/")
+		}
+		var w io.Writer = cmd.OutOrStdout()
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		count, err := dump.Export(db, path, w, opts)
+		if err != nil {
+			if output != "" {
+				os.Remove(output)
+			}
+			return fmt.Errorf("could not export: %v", err)
+		}
+		if output != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "exported %d records to %s\n", count, output)
+		}
+		return nil
+	},
+}
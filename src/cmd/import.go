@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/lunargon/bolt-tui/src/dump"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file> --from <data>",
+	Short: "Import JSON, JSON Lines or CSV records into a BoltDB file",
+	Long: `Import records written by the export command, or rows of a CSV file, into a
+BoltDB file, which is created if it does not exist. Buckets are created as
+needed and keys are written in batched transactions.
+
+--mode decides what happens to keys that already exist with another value:
+overwrite replaces the value, skip-existing keeps it and fail-on-conflict
+stops the import before anything is written. --dry-run prints the changes
+the import would make without writing them.
+
+CSV files start with a header naming their columns: bucket (the bucket path
+with "/" between names), key and value, with optional key_encoding and
+value_encoding columns (base64, hex, or a codec name for values given as
+JSON). Rows without a key create a bucket.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		format, _ := cmd.Flags().GetString("format")
+		mode, _ := cmd.Flags().GetString("mode")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		batch, _ := cmd.Flags().GetInt("batch-size")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if from == "" {
+			return fmt.Errorf("--from is required")
+		}
+		if readOnly, _ := cmd.Flags().GetBool("read-only"); readOnly && !dryRun {
+			return fmt.Errorf("import writes to the database, which --read-only only allows with --dry-run")
+		}
+
+		opts := dump.ImportOptions{Format: dump.FormatOf(from), DryRun: dryRun, BatchSize: batch}
+		var err error
+		if format != "" {
+			if opts.Format, err = dump.ParseFormat(format); err != nil {
+				return err
+			}
+		}
+		if opts.Mode, err = dump.ParseMode(mode); err != nil {
+			return err
+		}
+		protos, err := loadProto(cmd)
+		if err != nil {
+			return err
+		}
+		opts.Codec = func(name string) (codec.Codec, error) {
+			return lookupCodec(protos, name)
+		}
+
+		// The input is read twice when conflicts are looked for first, which
+		// standard input only allows once it is kept in memory
+		open := func() (io.ReadCloser, error) {
+			return os.Open(from)
+		}
+		if from == "-" {
+			data, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			open = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+		}
+
+		db := &bolt.DB{Path: args[0], ReadOnly: dryRun, Timeout: timeout}
+		if _, err := os.Stat(db.Path); dryRun && errors.Is(err, fs.ErrNotExist) {
+			// The import would create the file, so a dry run lists what it
+			// would write to an empty database
+			dir, err := os.MkdirTemp("", "bolt-tui-import-*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			db.Path, db.ReadOnly = filepath.Join(dir, "empty.db"), false
+		}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		run := func(opts dump.ImportOptions) (dump.ImportResult, error) {
+			r, err := open()
+			if err != nil {
+				return dump.ImportResult{}, err
+			}
+			defer r.Close()
+			return dump.Import(db, r, opts)
+		}
+
+		out := cmd.OutOrStdout()
+		if dryRun {
+			opts.Change = func(c dump.Change) {
+				fmt.Fprintln(out, c)
+			}
+		} else if opts.Mode == dump.FailOnConflict {
+			// Look for conflicts before writing, as batches are written as
+			// they are read
+			check := opts
+			check.DryRun = true
+			if _, err := run(check); err != nil {
+				return fmt.Errorf("nothing imported: %v", err)
+			}
+		}
+
+		result, err := run(opts)
+		if err != nil {
+			return fmt.Errorf("import stopped: %v", err)
+		}
+		if dryRun {
+			fmt.Fprintf(out, "dry run, nothing written: %s\n", result)
+		} else {
+			fmt.Fprintln(out, result)
+		}
+		return nil
+	},
+}
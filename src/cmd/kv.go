@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/dump"
+	"github.com/spf13/cobra"
+)
+
+// Encodings of keys, bucket names and values on the command line
+const (
+	encodingRaw    = "raw"
+	encodingHex    = dump.Hex
+	encodingBase64 = dump.Base64
+)
+
+// lsPageSize is the number of entries read per transaction by ls
+const lsPageSize = 1000
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [bucket/path]",
+	Short: "List the top-level buckets, or the keys and nested buckets of a bucket",
+	Long: `List the top-level buckets, or the keys and nested buckets of a bucket, one per
+line in key order. Nested buckets end with "/". With --json every entry is a
+JSON object with its key, whether it is a bucket and the size of its value.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		var path []string
+		if len(args) > 0 {
+			if path, err = parsePath(cmd, args[0]); err != nil {
+				return err
+			}
+		}
+		prefix, _ := cmd.Flags().GetString("prefix")
+		var r bolt.Range
+		if prefix != "" {
+			if r.Prefix, err = decodeArg(keyEncoding(cmd), prefix); err != nil {
+				return fmt.Errorf("invalid prefix: %v", err)
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		if len(path) == 0 {
+			buckets, err := db.GetBuckets(nil)
+			if err != nil {
+				return err
+			}
+			for _, name := range buckets {
+				if strings.HasPrefix(name, string(r.Prefix)) {
+					printEntry(cmd, out, bolt.Entry{Key: []byte(name), IsBucket: true})
+				}
+			}
+			return nil
+		}
+		var from []byte
+		for {
+			page, err := db.Scan(path, r, from, lsPageSize)
+			if err != nil {
+				return err
+			}
+			for _, e := range page.Entries {
+				printEntry(cmd, out, e)
+			}
+			if !page.HasNext {
+				return nil
+			}
+			from = bolt.After(page.Entries[len(page.Entries)-1].Key)
+		}
+	},
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get <bucket/path> <key>",
+	Short: "Print the value of a key",
+	Long: `Print the value of a key: as stored with the raw encoding, or in hex or base64
+followed by a newline. With --json the key and value are printed as a JSON
+object, with binary data in the chosen value encoding.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		path, key, err := parsePathKey(cmd, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		var value []byte
+		var exists, isBucket bool
+		err = db.View(func(tx *bolt.Tx) error {
+			if !tx.HasBucket(path) {
+				return fmt.Errorf("bucket %s not found", bolt.PathString(path))
+			}
+			v, ok, b := tx.Get(path, key)
+			value, exists, isBucket = append([]byte{}, v...), ok, b
+			return nil
+		})
+		switch {
+		case err != nil:
+			return err
+		case isBucket:
+			return fmt.Errorf("%s in %s is a bucket", args[1], args[0])
+		case !exists:
+			return fmt.Errorf("key %s not found in %s", args[1], args[0])
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON(cmd) {
+			return printJSON(out, map[string]dump.Data{
+				"key":   jsonData(key, keyEncoding(cmd)),
+				"value": jsonData(value, valueEncoding(cmd)),
+			})
+		}
+		encoding := valueEncoding(cmd)
+		if encoding == encodingRaw {
+			_, err = out.Write(value)
+			return err
+		}
+		_, err = fmt.Fprintln(out, encodeArg(encoding, value))
+		return err
+	},
+}
+
+var putCmd = &cobra.Command{
+	Use:   "put <bucket/path> <key> [value|-]",
+	Short: "Set the value of a key",
+	Long: `Set the value of a key in an existing bucket. The value is read from standard
+input when it is "-" or left out.`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, false)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		path, key, err := parsePathKey(cmd, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		var text string
+		if len(args) < 3 || args[2] == "-" {
+			b, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			text = string(b)
+			if valueEncoding(cmd) != encodingRaw {
+				text = strings.TrimSpace(text)
+			}
+		} else {
+			text = args[2]
+		}
+		value, err := decodeArg(valueEncoding(cmd), text)
+		if err != nil {
+			return fmt.Errorf("invalid value: %v", err)
+		}
+		return db.Update(func(tx *bolt.Tx) error {
+			if _, _, isBucket := tx.Get(path, key); isBucket {
+				return fmt.Errorf("%s in %s is a bucket", args[1], args[0])
+			}
+			return tx.Put(path, key, value)
+		})
+	},
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <bucket/path> <key>",
+	Short: "Delete a key",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, false)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		path, key, err := parsePathKey(cmd, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		return db.Update(func(tx *bolt.Tx) error {
+			_, exists, isBucket := tx.Get(path, key)
+			switch {
+			case isBucket:
+				return fmt.Errorf("%s in %s is a bucket, use rmbucket", args[1], args[0])
+			case !exists && force:
+				return nil
+			case !exists:
+				return fmt.Errorf("key %s not found in %s", args[1], args[0])
+			}
+			return tx.Delete(path, key)
+		})
+	},
+}
+
+var mkbucketCmd = &cobra.Command{
+	Use:   "mkbucket <bucket/path>",
+	Short: "Create a bucket and any missing parents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, false)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		path, err := parsePath(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return db.CreateBucket(path)
+	},
+}
+
+var rmbucketCmd = &cobra.Command{
+	Use:   "rmbucket <bucket/path>",
+	Short: "Delete a bucket with everything in it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openFile(cmd, false)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		path, err := parsePath(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return db.DeleteBucket(path)
+	},
+}
+
+// kvCommands are the commands that read or write single keys and buckets
+var kvCommands = []*cobra.Command{lsCmd, getCmd, putCmd, rmCmd, mkbucketCmd, rmbucketCmd}
+
+// openFile opens the database given with --file, which must exist. Commands
+// that write are refused under --read-only.
+func openFile(cmd *cobra.Command, readOnly bool) (*bolt.DB, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		return nil, fmt.Errorf("--file is required")
+	}
+	// Bolt would create a missing file, which is never what a typo means
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("could not open db: %v", err)
+	}
+	if flag, _ := cmd.Flags().GetBool("read-only"); flag && !readOnly {
+		return nil, fmt.Errorf("%s writes to the database, which --read-only does not allow", cmd.Name())
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	db := &bolt.DB{Path: path, ReadOnly: readOnly, Timeout: timeout}
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func keyEncoding(cmd *cobra.Command) string {
+	encoding, _ := cmd.Flags().GetString("key-encoding")
+	return encoding
+}
+
+func valueEncoding(cmd *cobra.Command) string {
+	encoding, _ := cmd.Flags().GetString("value-encoding")
+	return encoding
+}
+
+func asJSON(cmd *cobra.Command) bool {
+	b, _ := cmd.Flags().GetBool("json")
+	return b
+}
+
+// checkEncodings rejects unknown encodings before anything is read
+func checkEncodings(cmd *cobra.Command, args []string) error {
+	for _, encoding := range []string{keyEncoding(cmd), valueEncoding(cmd)} {
+		switch encoding {
+		case encodingRaw, encodingHex, encodingBase64:
+		default:
+			return fmt.Errorf("unknown encoding %q, expected raw, hex or base64", encoding)
+		}
+	}
+	return nil
+}
+
+// decodeArg converts an argument written in encoding to bytes
+func decodeArg(encoding, s string) ([]byte, error) {
+	switch encoding {
+	case encodingHex:
+		return hex.DecodeString(s)
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return []byte(s), nil
+	}
+}
+
+// encodeArg writes bytes in encoding
+func encodeArg(encoding string, b []byte) string {
+	switch encoding {
+	case encodingHex:
+		return hex.EncodeToString(b)
+	case encodingBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	default:
+		return string(b)
+	}
+}
+
+// parsePath splits a bucket path at "/" and decodes each name with the key
+// encoding
+func parsePath(cmd *cobra.Command, arg string) ([]string, error) {
+	var path []string
+	for _, part := range strings.Split(arg, "/") {
+		name, err := decodeArg(keyEncoding(cmd), part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket path %q: %v", arg, err)
+		}
+		if len(name) == 0 {
+			return nil, fmt.Errorf("invalid bucket path %q: empty bucket name", arg)
+		}
+		path = append(path, string(name))
+	}
+	return path, nil
+}
+
+// parsePathKey parses the bucket path and key arguments
+func parsePathKey(cmd *cobra.Command, pathArg, keyArg string) ([]string, []byte, error) {
+	path, err := parsePath(cmd, pathArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := decodeArg(keyEncoding(cmd), keyArg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid key %q: %v", keyArg, err)
+	}
+	if len(key) == 0 {
+		return nil, nil, fmt.Errorf("key cannot be empty")
+	}
+	return path, key, nil
+}
+
+// jsonData writes b as a JSON string when it is text, and otherwise in
+// encoding, or base64 for the raw encoding
+func jsonData(b []byte, encoding string) dump.Data {
+	if encoding == encodingRaw {
+		encoding = encodingBase64
+	}
+	return dump.NewData(b, encoding, nil)
+}
+
+// printEntry prints an entry listed by ls
+func printEntry(cmd *cobra.Command, out io.Writer, e bolt.Entry) {
+	if asJSON(cmd) {
+		entry := struct {
+			Key    dump.Data `json:"key"`
+			Bucket bool      `json:"bucket,omitempty"`
+			Size   *int      `json:"size,omitempty"`
+		}{Key: jsonData(e.Key, keyEncoding(cmd)), Bucket: e.IsBucket}
+		if !e.IsBucket {
+			size := len(e.Value)
+			entry.Size = &size
+		}
+		printJSON(out, entry)
+		return
+	}
+	line := encodeArg(keyEncoding(cmd), e.Key)
+	if e.IsBucket {
+		line += "/"
+	}
+	fmt.Fprintln(out, line)
+}
+
+// printJSON prints v as a line of JSON
+func printJSON(out io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", b)
+	return err
+}
@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/charmbracelet/bubbles/filepicker"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/lunargon/bolt-tui/src/app"
+	"github.com/lunargon/bolt-tui/src/dump"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +19,12 @@ var rootCmd = &cobra.Command{
 	Short: "A TUI for viewing and managing BoltDB files",
 	Long:  `A Terminal User Interface (TUI) for viewing and managing BoltDB files.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := appOptions(cmd)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// If a file path is provided, open it directly
 		filePath, _ := cmd.Flags().GetString("file")
 		if filePath != "" {
@@ -33,7 +42,7 @@ var rootCmd = &cobra.Command{
 			}
 
 			// Open the app directly with the provided file
-			appModel, err := app.New(absPath)
+			appModel, err := app.New(absPath, opts)
 			if err != nil {
 				fmt.Printf("Error opening database: %v\n", err)
 				os.Exit(1)
@@ -77,6 +86,7 @@ var rootCmd = &cobra.Command{
 
 		m := FilePickerModel{
 			filepicker: fp,
+			options:    opts,
 		}
 
 		p := tea.NewProgram(&m, tea.WithAltScreen())
@@ -91,6 +101,7 @@ var rootCmd = &cobra.Command{
 type FilePickerModel struct {
 	filepicker   filepicker.Model
 	selectedFile string
+	options      app.Options // Passed on to the app once a file is selected
 	quitting     bool
 	err          error
 }
@@ -118,7 +129,7 @@ func (m *FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.selectedFile = path
 
 		// Launch the main app with the selected file
-		appModel, err := app.New(path)
+		appModel, err := app.New(path, m.options)
 		if err != nil {
 			m.err = err
 			return m, nil
@@ -149,9 +160,56 @@ func (m *FilePickerModel) View() string {
 func Execute() {
 	rootCmd.PersistentFlags().StringP("file", "f", "", "Path to BoltDB file to open directly")
 	rootCmd.PersistentFlags().StringP("dir", "d", "", "Starting directory for file picker (use '.' for current directory)")
+	rootCmd.PersistentFlags().Bool("read-only", false, "Open the database read-only, without blocking other readers")
+	rootCmd.PersistentFlags().Bool("snapshot", false, "Browse a read-only copy of the database, e.g. when another process holds its lock")
+	rootCmd.PersistentFlags().Duration("timeout", 1*time.Second, "How long to wait for the lock of the database before showing the lock screen")
+	rootCmd.PersistentFlags().Bool("stage", false, "Start in staging mode, where edits are only written once reviewed and committed together")
+	rootCmd.PersistentFlags().StringSlice("proto", nil, "Protobuf .proto file or compiled descriptor set with the message types of values")
+	rootCmd.PersistentFlags().StringSlice("proto-path", nil, "Directory to look up imports of .proto files in")
+	rootCmd.PersistentFlags().StringArray("codec", nil, "Codec of the values of a bucket, as bucket/path=codec, where codec is json, msgpack, cbor, protowire, hex, raw or a protobuf message type")
+
+	compactCmd.Flags().Int("batch-size", 10000, "Keys and buckets written per transaction")
+	compactCmd.Flags().Float64("fill-percent", 0.5, "How full to pack pages, from 0.1 to 1 (1 suits files that are mostly read)")
+	compactCmd.Flags().BoolP("quiet", "q", false, "Do not report progress")
+
+	exportCmd.Flags().StringP("output", "o", "", "File to write the export to instead of standard output")
+	exportCmd.Flags().String("format", "", "Format of the export, json or jsonl (default: json for .json output files, jsonl otherwise)")
+	exportCmd.Flags().String("binary", dump.Base64, "Encoding of keys and values that are not UTF-8 text, base64 or hex")
+	exportCmd.Flags().String("decode", "", "Codec to write values decoded with, or auto to detect it for each value")
+
+	importCmd.Flags().String("from", "", "File to import, or - for standard input")
+	importCmd.Flags().String("format", "", "Format of the input, json, jsonl or csv (default: from the file extension, jsonl otherwise)")
+	importCmd.Flags().String("mode", dump.Overwrite.String(), "What to do with existing keys that have another value: overwrite, skip-existing or fail-on-conflict")
+	importCmd.Flags().Bool("dry-run", false, "Print the changes the import would make without writing them")
+	importCmd.Flags().Int("batch-size", 1000, "Records written per transaction")
+
+	execCmd.Flags().Bool("dry-run", false, "List the changes of the script without writing them")
+
+	diffCmd.Flags().BoolP("quiet", "q", false, "Only report whether the files differ")
+	diffCmd.Flags().Bool("tui", false, "Browse the differences side by side")
+
+	for _, c := range kvCommands {
+		c.Flags().String("key-encoding", encodingRaw, "Encoding of bucket names and keys in arguments and output: raw, hex or base64")
+		c.Flags().String("value-encoding", encodingRaw, "Encoding of values in arguments and output: raw, hex or base64")
+		c.PreRunE = checkEncodings
+		c.SilenceUsage = true
+		c.SilenceErrors = true
+	}
+	lsCmd.Flags().String("prefix", "", "Only list keys starting with the prefix")
+	lsCmd.Flags().Bool("json", false, "Print each entry as a line of JSON")
+	getCmd.Flags().Bool("json", false, "Print the key and value as JSON")
+	rmCmd.Flags().Bool("force", false, "Do not fail when the key does not exist")
+
+	rootCmd.AddCommand(statsCmd, compactCmd, checkCmd, diffCmd, exportCmd, importCmd, shellCmd, execCmd)
+	rootCmd.AddCommand(kvCommands...)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/app"
+	"github.com/lunargon/bolt-tui/src/codec"
+	"github.com/spf13/cobra"
+)
+
+// appOptions builds the application options from the command flags
+func appOptions(cmd *cobra.Command) (app.Options, error) {
+	var opts app.Options
+	opts.ReadOnly, _ = cmd.Flags().GetBool("read-only")
+	opts.Snapshot, _ = cmd.Flags().GetBool("snapshot")
+	opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	opts.Staging, _ = cmd.Flags().GetBool("stage")
+
+	protos, err := loadProto(cmd)
+	if err != nil {
+		return opts, err
+	}
+	// Values of buckets not mapped to a message type are likely messages too
+	opts.WireFallback = protos != nil
+
+	mappings, _ := cmd.Flags().GetStringArray("codec")
+	for _, mapping := range mappings {
+		path, name, ok := strings.Cut(mapping, "=")
+		if !ok || path == "" || name == "" {
+			return opts, fmt.Errorf("invalid codec mapping %q, expected bucket/path=codec", mapping)
+		}
+		c, err := lookupCodec(protos, name)
+		if err != nil {
+			return opts, err
+		}
+		if opts.Codecs == nil {
+			opts.Codecs = make(map[string]codec.Codec)
+		}
+		opts.Codecs[path] = c
+	}
+	return opts, nil
+}
+
+// loadProto loads the protobuf files given with --proto, if any
+func loadProto(cmd *cobra.Command) (*codec.ProtoFiles, error) {
+	protoFiles, _ := cmd.Flags().GetStringSlice("proto")
+	importPaths, _ := cmd.Flags().GetStringSlice("proto-path")
+	if len(protoFiles) == 0 {
+		return nil, nil
+	}
+	return codec.LoadProto(protoFiles, importPaths)
+}
+
+// lookupCodec finds a registered codec, or a protobuf message type when
+// proto files were loaded
+func lookupCodec(protos *codec.ProtoFiles, name string) (codec.Codec, error) {
+	c, err := codec.Lookup(name)
+	if err == nil || protos == nil {
+		return c, err
+	}
+	return protos.Codec(name)
+}
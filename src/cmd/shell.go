@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/shell"
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <file>",
+	Short: "Run commands on a BoltDB file in an interactive shell",
+	Long: `Run commands on a BoltDB file in an interactive shell: cd, ls, get, put, rm,
+mv, cp, stat, and begin, commit and rollback to group changes in a transaction.
+Tab completes commands, bucket paths and keys. Type help for the list of
+commands.
+
+The file stays locked while the shell is open. Commands are read from standard
+input when it is not a terminal.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Bolt would create a missing file, which is never what a typo means
+		if _, err := os.Stat(args[0]); err != nil {
+			return fmt.Errorf("could not open db: %v", err)
+		}
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		db := &bolt.DB{Path: args[0], ReadOnly: readOnly, Timeout: timeout}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		sh := shell.New(db, cmd.OutOrStdout())
+		line := liner.NewLiner()
+		defer line.Close()
+		line.SetCtrlCAborts(true)
+		line.SetTabCompletionStyle(liner.TabPrints)
+		line.SetWordCompleter(sh.Complete)
+
+		for {
+			input, err := line.Prompt(sh.Prompt())
+			if err == liner.ErrPromptAborted {
+				continue
+			}
+			if err == io.EOF {
+				fmt.Fprintln(cmd.ErrOrStderr())
+				break
+			}
+			if err != nil {
+				return err
+			}
+			line.AppendHistory(input)
+			err = sh.Run(input)
+			if errors.Is(err, shell.ErrExit) {
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "error: %v\n", err)
+			}
+		}
+		if sh.InTx() {
+			fmt.Fprintln(cmd.ErrOrStderr(), "The open transaction was rolled back")
+		}
+		return sh.Close()
+	},
+}
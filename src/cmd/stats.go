@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <file>",
+	Short: "Show page and bucket statistics of a BoltDB file",
+	Long: `Show how the space of a BoltDB file is used: the file and freelist sizes, and for
+every bucket its size, fill ratio, tree depth and key count, largest first.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		db := &bolt.DB{Path: args[0], ReadOnly: true, Timeout: timeout}
+		if err := db.Open(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stats, err := db.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), stats)
+		return nil
+	},
+}
@@ -0,0 +1,131 @@
+package codec
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Codec converts stored values to a tree of plain Go values and back, so that
+// encoded values can be shown and edited as JSON. A tree is made of nil, bool,
+// int64, uint64, float64, string, []byte, []any and map[string]any.
+type Codec interface {
+	// Name identifies the codec, e.g. in flags and in the UI
+	Name() string
+	// Detect reports whether value looks like it was written by the codec
+	Detect(value []byte) bool
+	// Decode converts a stored value to a tree
+	Decode(value []byte) (any, error)
+	// Encode converts a tree back to a stored value
+	Encode(tree any) ([]byte, error)
+}
+
+var registry []Codec
+
+// Register adds a codec to the registry. Codecs are tried by Detect in the
+// order they were registered. It panics if the name is already taken.
+func Register(c Codec) {
+	if _, err := Lookup(c.Name()); err == nil {
+		panic(fmt.Sprintf("codec %q registered twice", c.Name()))
+	}
+	registry = append(registry, c)
+}
+
+// Lookup returns the registered codec with the given name
+func Lookup(name string) (Codec, error) {
+	for _, c := range registry {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown codec %q, expected one of %v", name, Names())
+}
+
+// Names returns the names of the registered codecs in registration order
+func Names() []string {
+	names := make([]string, len(registry))
+	for i, c := range registry {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// Detect returns the first registered codec that recognises value, or Raw
+func Detect(value []byte) Codec {
+	for _, c := range registry {
+		if c.Detect(value) {
+			return c
+		}
+	}
+	return Raw
+}
+
+func init() {
+	Register(JSON)
+	Register(MessagePack)
+	Register(CBOR)
+	Register(ProtoWire)
+	Register(Hex)
+	Register(Raw)
+}
+
+// plain converts decoded values to tree values. Maps with non-string keys get
+// their keys formatted, and types JSON cannot show, such as timestamps and
+// big numbers, are turned into strings.
+func plain(v any) any {
+	switch v := v.(type) {
+	case nil, bool, int64, uint64, float64, string, []byte:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case float32:
+		return float64(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = plain(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = plain(e)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			if s, ok := k.(string); ok {
+				out[s] = plain(e)
+			} else {
+				out[fmt.Sprint(k)] = plain(e)
+			}
+		}
+		return out
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
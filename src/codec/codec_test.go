@@ -0,0 +1,129 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tree := map[string]any{
+		"id":    int64(-7),
+		"count": uint64(1 << 40),
+		"ratio": 1.5,
+		"name":  "alice",
+		"ok":    true,
+		"none":  nil,
+		"tags":  []any{"a", int64(2)},
+		"raw":   []byte{0, 1, 0xff},
+		"inner": map[string]any{"x": int64(1)},
+	}
+	tests := []struct {
+		codec Codec
+		tree  any
+	}{
+		{MessagePack, tree},
+		{CBOR, tree},
+		{JSON, map[string]any{"name": "alice", "n": int64(3), "list": []any{1.5, false}}},
+		{Hex, "00 01 ff"},
+		{Raw, "plain text"},
+		{Raw, []byte{0xff, 0}},
+		{ProtoWire, []any{
+			map[string]any{"field": int64(1), "varint": uint64(150)},
+			map[string]any{"field": int64(2), "string": "alice"},
+			map[string]any{"field": int64(3), "message": []any{
+				map[string]any{"field": int64(1), "fixed32": uint64(7)},
+			}},
+			map[string]any{"field": int64(4), "bytes": []byte{0xff, 0xfe}},
+			map[string]any{"field": int64(5), "fixed64": uint64(9)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec.Name(), func(t *testing.T) {
+			value, err := tt.codec.Encode(tt.tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The way values go through the editor: decoded, shown as
+			// JSON, parsed back and encoded
+			decoded, err := tt.codec.Decode(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := Format(decoded, "  ")
+			parsed, err := Parse(text)
+			if err != nil {
+				t.Fatalf("could not parse %s: %v", text, err)
+			}
+			again, err := tt.codec.Encode(parsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again, value) {
+				t.Errorf("%x became %x after going through\n%s", value, again, text)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	msgpack, err := MessagePack.Encode(map[string]any{"a": int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cbor, err := CBOR.Encode([]any{int64(1), "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scalar, err := MessagePack.Encode(int64(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := ProtoWire.Encode([]any{map[string]any{"field": int64(1), "varint": uint64(150)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value []byte
+		want  Codec
+	}{
+		{"JSON object", []byte(`{"a": 1}`), JSON},
+		{"JSON array", []byte(`[1, 2]`), JSON},
+		{"MessagePack map", msgpack, MessagePack},
+		{"CBOR array", cbor, CBOR},
+		{"MessagePack scalar", scalar, Raw},
+		{"protobuf message", message, Raw},
+		{"text", []byte("hello"), Raw},
+		{"empty", nil, Raw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.value); got != tt.want {
+				t.Errorf("Detect(%x) = %s, want %s", tt.value, got.Name(), tt.want.Name())
+			}
+		})
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"hello", true},
+		{"", true},
+		{"tabs\tand\r\nlines", true},
+		{"héllo ✓", true},
+		{"nul\x00", false},
+		{"escape\x1b[0m", false},
+		{"\xff\xfe", false},
+		{"zero​width", false},
+	}
+	for _, tt := range tests {
+		if got := IsText([]byte(tt.value)); got != tt.want {
+			t.Errorf("IsText(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
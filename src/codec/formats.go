@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The built-in codecs
+var (
+	JSON        Codec = jsonCodec{}
+	MessagePack Codec = msgpackCodec{}
+	CBOR        Codec = cborCodec{}
+	Hex         Codec = hexCodec{}
+	Raw         Codec = rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Detect(value []byte) bool {
+	return isContainer(value) && json.Valid(value)
+}
+
+func (jsonCodec) Decode(value []byte) (any, error) {
+	return Parse(string(value))
+}
+
+func (jsonCodec) Encode(tree any) ([]byte, error) {
+	// Byte strings have no JSON form other than the one Format uses
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(Format(tree, ""))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+// Detect only accepts maps and arrays, as nearly any short value is a valid
+// MessagePack scalar
+func (c msgpackCodec) Detect(value []byte) bool {
+	if len(value) == 0 || !(value[0] >= 0x80 && value[0] <= 0x9f || value[0] >= 0xdc && value[0] <= 0xdf) {
+		return false
+	}
+	_, err := c.Decode(value)
+	return err == nil
+}
+
+func (msgpackCodec) Decode(value []byte) (any, error) {
+	r := bytes.NewReader(value)
+	dec := msgpack.NewDecoder(r)
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (any, error) {
+		return d.DecodeUntypedMap()
+	})
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("msgpack: %d bytes of extra data", r.Len())
+	}
+	return plain(v), nil
+}
+
+func (msgpackCodec) Encode(tree any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return "cbor" }
+
+var cborEncMode, _ = cbor.EncOptions{Sort: cbor.SortBytewiseLexical}.EncMode()
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[any]any(nil)),
+}.DecMode()
+
+// Detect only accepts maps and arrays, like the MessagePack codec
+func (c cborCodec) Detect(value []byte) bool {
+	if len(value) == 0 || value[0]>>5 != 4 && value[0]>>5 != 5 {
+		return false
+	}
+	_, err := c.Decode(value)
+	return err == nil
+}
+
+func (cborCodec) Decode(value []byte) (any, error) {
+	var v any
+	if err := cborDecMode.Unmarshal(value, &v); err != nil {
+		return nil, err
+	}
+	return plain(v), nil
+}
+
+func (cborCodec) Encode(tree any) ([]byte, error) {
+	return cborEncMode.Marshal(tree)
+}
+
+// hexCodec shows values as a string of hex digits
+type hexCodec struct{}
+
+func (hexCodec) Name() string { return "hex" }
+
+func (hexCodec) Detect(value []byte) bool { return false }
+
+func (hexCodec) Decode(value []byte) (any, error) {
+	return hex.EncodeToString(value), nil
+}
+
+func (hexCodec) Encode(tree any) ([]byte, error) {
+	s, ok := tree.(string)
+	if !ok {
+		return nil, fmt.Errorf("hex: expected a string of hex digits, got %T", tree)
+	}
+	return hex.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// rawCodec leaves values as they are stored: text as a string, anything
+// else as bytes. It is the fallback when no other codec recognises a value.
+type rawCodec struct{}
+
+func (rawCodec) Name() string { return "raw" }
+
+func (rawCodec) Detect(value []byte) bool { return true }
+
+func (rawCodec) Decode(value []byte) (any, error) {
+	if utf8.Valid(value) {
+		return string(value), nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (rawCodec) Encode(tree any) ([]byte, error) {
+	switch v := tree.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, fmt.Errorf("raw: expected a string or bytes, got %T", tree)
+}
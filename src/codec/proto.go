@@ -0,0 +1,169 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoFiles holds the message types of .proto files and descriptor sets
+type ProtoFiles struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// LoadProto loads message types from .proto source files, which are compiled
+// with their imports looked up in importPaths, and from FileDescriptorSets as
+// written by protoc --descriptor_set_out. Files are told apart by extension.
+func LoadProto(paths, importPaths []string) (*ProtoFiles, error) {
+	files := new(protoregistry.Files)
+	var sources []string
+	for _, path := range paths {
+		if filepath.Ext(path) == ".proto" {
+			sources = append(sources, path)
+			continue
+		}
+		if err := loadDescriptorSet(files, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(sources) > 0 {
+		if len(importPaths) == 0 {
+			// Resolve the files and their imports from where they are
+			importPaths = []string{"."}
+			for _, path := range sources {
+				importPaths = append(importPaths, filepath.Dir(path))
+			}
+		}
+		for i, path := range sources {
+			sources[i] = relativeTo(importPaths, path)
+		}
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+		}
+		compiled, err := compiler.Compile(context.Background(), sources...)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile proto files: %v", err)
+		}
+		for _, fd := range compiled {
+			if err := registerFile(files, fd); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &ProtoFiles{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// relativeTo returns path relative to the first import path containing it, as
+// the compiler expects
+func relativeTo(importPaths []string, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	for _, dir := range importPaths {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, abs); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// loadDescriptorSet adds the files of a serialized FileDescriptorSet
+func loadDescriptorSet(files *protoregistry.Files, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("could not read descriptor set %s: %v", path, err)
+	}
+	parsed, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return fmt.Errorf("invalid descriptor set %s: %v", path, err)
+	}
+	var rerr error
+	parsed.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		rerr = registerFile(files, fd)
+		return rerr == nil
+	})
+	return rerr
+}
+
+// registerFile adds a file and its imports, skipping files already known
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// Codec returns a codec for the message type with the given full name, e.g.
+// "acme.v1.Session"
+func (p *ProtoFiles) Codec(name string) (Codec, error) {
+	d, err := p.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message type %q", name)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message type", name)
+	}
+	return protoCodec{md: md, types: p.types}, nil
+}
+
+// protoCodec decodes messages of a single type. The tree is the protojson
+// form of the message, so edits use the field names of the schema.
+type protoCodec struct {
+	md    protoreflect.MessageDescriptor
+	types *dynamicpb.Types
+}
+
+func (c protoCodec) Name() string { return string(c.md.FullName()) }
+
+// Detect never claims values: any bytes may parse as some message, so proto
+// codecs are only used for the buckets they are mapped to
+func (protoCodec) Detect(value []byte) bool { return false }
+
+func (c protoCodec) Decode(value []byte) (any, error) {
+	msg := dynamicpb.NewMessage(c.md)
+	if err := (proto.UnmarshalOptions{Resolver: c.types}).Unmarshal(value, msg); err != nil {
+		return nil, err
+	}
+	out, err := protojson.MarshalOptions{Resolver: c.types}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(out))
+}
+
+func (c protoCodec) Encode(tree any) ([]byte, error) {
+	msg := dynamicpb.NewMessage(c.md)
+	if err := (protojson.UnmarshalOptions{Resolver: c.types}).Unmarshal([]byte(Format(tree, "")), msg); err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
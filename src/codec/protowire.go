@@ -0,0 +1,161 @@
+package codec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtoWire shows protobuf messages without a schema: a list of fields, each
+// with its number and one value keyed by wire type. Length-delimited values
+// are shown as text, as a nested message when they parse as one, or as bytes:
+//
+//	[{"field": 1, "varint": 150}, {"field": 2, "string": "alice"}]
+var ProtoWire Codec = protowireCodec{}
+
+type protowireCodec struct{}
+
+func (protowireCodec) Name() string { return "protowire" }
+
+// Detect never matches: nearly any binary value parses as protobuf fields, so
+// the codec is only used when it is chosen for a bucket
+func (protowireCodec) Detect(value []byte) bool { return false }
+
+func (protowireCodec) Decode(value []byte) (any, error) {
+	fields, err := decodeWire(value)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (protowireCodec) Encode(tree any) ([]byte, error) {
+	return encodeWire(nil, tree)
+}
+
+// decodeWire splits a message into its fields
+func decodeWire(b []byte) ([]any, error) {
+	fields := []any{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		field := map[string]any{"field": int64(num)}
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			field["varint"] = v
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			field["fixed32"] = uint64(v)
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(b)
+			field["fixed64"] = v
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				if IsText(v) {
+					field["string"] = string(v)
+				} else if nested, err := decodeWire(v); err == nil {
+					field["message"] = nested
+				} else {
+					field["bytes"] = append([]byte{}, v...)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("field %d: groups are not supported", num)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// encodeWire appends the fields of a tree written by decodeWire to b
+func encodeWire(b []byte, tree any) ([]byte, error) {
+	fields, ok := tree.([]any)
+	if !ok {
+		return nil, fmt.Errorf("protowire: expected a list of fields, got %T", tree)
+	}
+	for i, f := range fields {
+		field, ok := f.(map[string]any)
+		if !ok || len(field) != 2 {
+			return nil, fmt.Errorf("protowire: field %d must have a number and a single value", i)
+		}
+		num, err := toUint64(field["field"])
+		if err != nil || num < 1 || num > uint64(protowire.MaxValidNumber) {
+			return nil, fmt.Errorf("protowire: field %d has an invalid number", i)
+		}
+		n := protowire.Number(num)
+
+		switch {
+		case field["varint"] != nil:
+			v, err := toUint64(field["varint"])
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, n, protowire.VarintType)
+			b = protowire.AppendVarint(b, v)
+		case field["fixed32"] != nil:
+			v, err := toUint64(field["fixed32"])
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, n, protowire.Fixed32Type)
+			b = protowire.AppendFixed32(b, uint32(v))
+		case field["fixed64"] != nil:
+			v, err := toUint64(field["fixed64"])
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, n, protowire.Fixed64Type)
+			b = protowire.AppendFixed64(b, v)
+		case field["string"] != nil:
+			s, ok := field["string"].(string)
+			if !ok {
+				return nil, fmt.Errorf("protowire: field %d: string expected", num)
+			}
+			b = protowire.AppendTag(b, n, protowire.BytesType)
+			b = protowire.AppendString(b, s)
+		case field["bytes"] != nil:
+			v, ok := field["bytes"].([]byte)
+			if !ok {
+				return nil, fmt.Errorf("protowire: field %d: bytes expected", num)
+			}
+			b = protowire.AppendTag(b, n, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		case field["message"] != nil:
+			nested, err := encodeWire(nil, field["message"])
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, n, protowire.BytesType)
+			b = protowire.AppendBytes(b, nested)
+		default:
+			return nil, fmt.Errorf("protowire: field %d has no value", num)
+		}
+	}
+	return b, nil
+}
+
+// toUint64 converts a tree number to the bits of a wire value. Negative
+// integers keep their two's complement form, as protobuf encodes them.
+func toUint64(v any) (uint64, error) {
+	switch v := v.(type) {
+	case int64:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("protowire: expected an integer, got %v", v)
+}
@@ -0,0 +1,192 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bytesKey marks a byte string in the JSON form of a tree, as JSON only has
+// text strings: {"$bytes": "<base64>"}
+const bytesKey = "$bytes"
+
+// IsText reports whether b is valid UTF-8 made of printable runes and
+// whitespace, so it can be shown as is rather than escaped
+func IsText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format renders a tree as JSON with sorted keys. Each nesting level is
+// indented with indent, or everything is kept on one line when it is empty.
+// Floats always keep a decimal point so that they are parsed back as floats.
+func Format(tree any, indent string) string {
+	var b strings.Builder
+	format(&b, tree, indent, 0)
+	return b.String()
+}
+
+func format(b *strings.Builder, v any, indent string, depth int) {
+	newline := func(depth int) {
+		if indent != "" {
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(indent, depth))
+		}
+	}
+	sep := ","
+	colon := ":"
+	if indent == "" {
+		sep, colon = ", ", ": "
+	}
+
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		b.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// Not representable in JSON
+			writeString(b, strconv.FormatFloat(v, 'g', -1, 64))
+			return
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		b.WriteString(s)
+	case string:
+		writeString(b, v)
+	case []byte:
+		format(b, map[string]any{bytesKey: base64.StdEncoding.EncodeToString(v)}, indent, depth)
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			format(b, e, indent, depth+1)
+		}
+		newline(depth)
+		b.WriteByte(']')
+	case map[string]any:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteByte('{')
+		for i, k := range sortedKeys(v) {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			writeString(b, k)
+			b.WriteString(colon)
+			if indent != "" {
+				b.WriteByte(' ')
+			}
+			format(b, v[k], indent, depth+1)
+		}
+		newline(depth)
+		b.WriteByte('}')
+	default:
+		writeString(b, fmt.Sprint(v))
+	}
+}
+
+func writeString(b *strings.Builder, s string) {
+	quoted, _ := json.Marshal(s)
+	b.Write(quoted)
+}
+
+// Parse reads the JSON form of a tree as written by Format. Numbers without
+// a decimal point or exponent are read as integers.
+func Parse(text string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return fromJSON(v)
+}
+
+// fromJSON converts a document decoded with UseNumber to a tree
+func fromJSON(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return parseNumber(string(v))
+	case []any:
+		for i, e := range v {
+			t, err := fromJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = t
+		}
+		return v, nil
+	case map[string]any:
+		if s, ok := v[bytesKey].(string); ok && len(v) == 1 {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %v", bytesKey, err)
+			}
+			return b, nil
+		}
+		for k, e := range v {
+			t, err := fromJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = t
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// parseNumber reads a JSON number as the narrowest tree type holding it
+func parseNumber(s string) (any, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// isContainer reports whether the first byte of a JSON document opens an
+// object or an array
+func isContainer(value []byte) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) > 0 && (value[0] == '{' || value[0] == '[')
+}
@@ -0,0 +1,43 @@
+package diff
+
+// mergeGap is the most equal bytes between two differing runs that are
+// reported as one
+const mergeGap = 4
+
+// Range is a run of bytes that differ between two values at the same offset
+type Range struct {
+	Offset int
+	Old    []byte // Bytes of the first value, shorter or empty past its end
+	New    []byte // Bytes of the second value, shorter or empty past its end
+}
+
+// Bytes compares two values byte by byte at the same offsets, and returns
+// the runs that differ, including the tail of the longer value. Runs close
+// to each other are merged.
+func Bytes(a, b []byte) []Range {
+	n := max(len(a), len(b))
+	same := func(i int) bool {
+		return i < len(a) && i < len(b) && a[i] == b[i]
+	}
+
+	var ranges []Range
+	for i := 0; i < n; {
+		if same(i) {
+			i++
+			continue
+		}
+		end := i + 1
+		for j := end; j < n && j < end+mergeGap; j++ {
+			if !same(j) {
+				end = j + 1
+			}
+		}
+		ranges = append(ranges, Range{
+			Offset: i,
+			Old:    a[min(i, len(a)):min(end, len(a))],
+			New:    b[min(i, len(b)):min(end, len(b))],
+		})
+		i = end
+	}
+	return ranges
+}
@@ -0,0 +1,95 @@
+// Package diff compares values line by line and byte by byte
+package diff
+
+// Op is what happened to a line of a diff
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Line is a line of a line-by-line diff
+type Line struct {
+	Op   Op
+	Text string
+}
+
+const maxCells = 1 << 20 // Largest LCS table computed before falling back
+
+// Lines returns the lines of a and b as a sequence of unchanged, deleted and
+// inserted lines. Common leading and trailing lines are matched first, and
+// the rest is aligned with a longest common subsequence when it is small
+// enough, otherwise it is reported as entirely replaced.
+func Lines(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var out []Line
+	for _, line := range a[:prefix] {
+		out = append(out, Line{Equal, line})
+	}
+	out = append(out, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		out = append(out, Line{Equal, line})
+	}
+	return out
+}
+
+// middle aligns the differing part of two documents
+func middle(a, b []string) []Line {
+	var out []Line
+	if len(a)*len(b) > maxCells {
+		for _, line := range a {
+			out = append(out, Line{Delete, line})
+		}
+		for _, line := range b {
+			out = append(out, Line{Insert, line})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Equal, a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Delete, a[i]})
+			i++
+		default:
+			out = append(out, Line{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, Line{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, Line{Insert, b[j]})
+	}
+	return out
+}
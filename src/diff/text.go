@@ -0,0 +1,35 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Split returns the lines of a text value. JSON objects and arrays are
+// indented first, so that a changed field shows as a changed line.
+func Split(v []byte) []string {
+	trimmed := bytes.TrimSpace(v)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, trimmed, "", "  "); err == nil {
+			return strings.Split(indented.String(), "\n")
+		}
+	}
+	return strings.Split(string(v), "\n")
+}
+
+// Context marks the lines of a diff worth showing: the changed lines and up
+// to n unchanged lines on each side of them
+func Context(lines []Line, n int) []bool {
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == Equal {
+			continue
+		}
+		for j := max(0, i-n); j <= min(len(lines)-1, i+n); j++ {
+			show[j] = true
+		}
+	}
+	return show
+}
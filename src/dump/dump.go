@@ -0,0 +1,190 @@
+// Package dump converts the contents of BoltDB files to and from JSON, to
+// share fixtures and review changes to data as text.
+package dump
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// Format is the layout of an export
+type Format int
+
+const (
+	JSONL Format = iota // One record per line
+	JSON                // An indented array of records
+	CSV                 // Rows of bucket, key and value, only read
+)
+
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "json"
+	case CSV:
+		return "csv"
+	default:
+		return "jsonl"
+	}
+}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "jsonl":
+		return JSONL, nil
+	case "json":
+		return JSON, nil
+	case "csv":
+		return CSV, nil
+	}
+	return JSONL, fmt.Errorf("unknown format %q, expected json, jsonl or csv", name)
+}
+
+// FormatOf guesses the format of a file from its extension: JSON for .json,
+// CSV for .csv and JSON Lines otherwise
+func FormatOf(file string) Format {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return JSON
+	case ".csv":
+		return CSV
+	default:
+		return JSONL
+	}
+}
+
+// Binary encodings of data that is not UTF-8 text
+const (
+	Base64 = "base64"
+	Hex    = "hex"
+)
+
+// Record is an item of an export: a bucket when Key is nil, otherwise a key
+// and its value in the bucket at Bucket. Buckets come before their contents.
+type Record struct {
+	Bucket []Data `json:"bucket"`
+	Key    *Data  `json:"key,omitempty"`
+	Value  *Data  `json:"value,omitempty"`
+}
+
+// Data is a bucket name, key or value. It is written as a string when it is
+// printable UTF-8 text, and otherwise as an object with a single field naming how it is
+// written: {"base64": "..."} and {"hex": "..."} hold binary data, while the
+// name of a codec holds the decoded value, e.g. {"msgpack": {"id": 1}}.
+type Data struct {
+	Bytes    []byte
+	Encoding string // Base64, Hex or the name of Codec, empty for text
+	Codec    codec.Codec
+	Tree     any // Value decoded with Codec
+}
+
+// NewData chooses how b is written. Values are written decoded with c when
+// it is set and encoding the decoded value gives back the same bytes, so
+// that nothing is lost; binary is the encoding of other binary data.
+func NewData(b []byte, binary string, c codec.Codec) Data {
+	if c != nil && c != codec.Raw {
+		if tree, err := c.Decode(b); err == nil {
+			if again, err := c.Encode(tree); err == nil && string(again) == string(b) {
+				return Data{Bytes: b, Encoding: c.Name(), Codec: c, Tree: tree}
+			}
+		}
+	}
+	if codec.IsText(b) {
+		return Data{Bytes: b}
+	}
+	return Data{Bytes: b, Encoding: binary}
+}
+
+// MarshalJSON writes the data as a string or an object naming its encoding
+func (d Data) MarshalJSON() ([]byte, error) {
+	var content []byte
+	var err error
+	switch d.Encoding {
+	case "":
+		return json.Marshal(string(d.Bytes))
+	case Base64:
+		content, err = json.Marshal(base64.StdEncoding.EncodeToString(d.Bytes))
+	case Hex:
+		content, err = json.Marshal(hex.EncodeToString(d.Bytes))
+	default:
+		content = []byte(codec.Format(d.Tree, ""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	name, err := json.Marshal(d.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "{%s:%s}", name, content), nil
+}
+
+// UnmarshalJSON reads data written by MarshalJSON. Decoded values are kept
+// as trees until resolve encodes them.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err == nil {
+		*d = Data{Bytes: []byte(text)}
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || len(fields) != 1 {
+		return fmt.Errorf("expected a string or an object with a single field, got %s", b)
+	}
+	for name, content := range fields {
+		if name == Base64 || name == Hex {
+			if err := json.Unmarshal(content, &text); err != nil {
+				return fmt.Errorf("%s data must be a string, got %s", name, content)
+			}
+		} else {
+			text = string(content)
+		}
+		var err error
+		*d, err = encodedData(name, text)
+		return err
+	}
+	return nil
+}
+
+// encodedData reads data written with encoding: base64 or hex digits, or
+// the JSON of a value decoded with the codec named encoding
+func encodedData(encoding, text string) (Data, error) {
+	d := Data{Encoding: encoding}
+	var err error
+	switch encoding {
+	case "":
+		d.Bytes = []byte(text)
+	case Base64:
+		d.Bytes, err = base64.StdEncoding.DecodeString(text)
+	case Hex:
+		d.Bytes, err = hex.DecodeString(text)
+	default:
+		d.Tree, err = codec.Parse(text)
+	}
+	if err != nil {
+		return d, fmt.Errorf("invalid %s data: %v", encoding, err)
+	}
+	return d, nil
+}
+
+// resolve encodes decoded values with the codec named by their encoding
+func (d *Data) resolve(lookup func(name string) (codec.Codec, error)) error {
+	if d.Encoding == "" || d.Encoding == Base64 || d.Encoding == Hex {
+		return nil
+	}
+	c, err := lookup(d.Encoding)
+	if err != nil {
+		return err
+	}
+	if d.Bytes, err = c.Encode(d.Tree); err != nil {
+		return fmt.Errorf("could not encode %s value: %v", c.Name(), err)
+	}
+	d.Codec = c
+	return nil
+}
@@ -0,0 +1,110 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	Format Format
+	// Binary is how data that is not UTF-8 text is written, Base64 when empty
+	Binary string
+	// Codec returns the codec to decode a value of the bucket at path with,
+	// or nil to leave it as stored. Values are only written decoded when
+	// encoding them again gives back the same bytes.
+	Codec func(path []string, value []byte) codec.Codec
+}
+
+// Export writes the buckets, nested buckets and keys of the bucket at path,
+// or of the whole database when path is empty, as they are read. It returns
+// the number of records written.
+func Export(db *bolt.DB, path []string, w io.Writer, opts ExportOptions) (int, error) {
+	if opts.Binary == "" {
+		opts.Binary = Base64
+	}
+	if opts.Binary != Base64 && opts.Binary != Hex {
+		return 0, fmt.Errorf("unknown binary encoding %q, expected base64 or hex", opts.Binary)
+	}
+	if opts.Format == CSV {
+		return 0, fmt.Errorf("cannot export to %s, expected json or jsonl", opts.Format)
+	}
+
+	e := &exporter{w: bufio.NewWriter(w), opts: opts}
+	if opts.Format == JSON {
+		e.w.WriteString("[")
+	}
+	var err error
+	if len(path) == 0 {
+		err = db.Walk(e.write)
+	} else {
+		// Start with the bucket itself, so that it is created on import
+		// even when it is empty
+		if err = e.write(path[:len(path)-1], []byte(path[len(path)-1]), nil); err == nil {
+			err = db.WalkBucket(path, e.write)
+		}
+	}
+	if err != nil {
+		return e.count, err
+	}
+	if opts.Format == JSON {
+		if e.count > 0 {
+			e.w.WriteString("\n")
+		}
+		e.w.WriteString("]\n")
+	}
+	return e.count, e.w.Flush()
+}
+
+// exporter writes the records of an export
+type exporter struct {
+	w     *bufio.Writer
+	opts  ExportOptions
+	count int
+}
+
+// write writes the record of an item visited by a walk
+func (e *exporter) write(path []string, k, v []byte) error {
+	var r Record
+	if v == nil {
+		path = append(path[:len(path):len(path)], string(k))
+	} else {
+		var c codec.Codec
+		if e.opts.Codec != nil {
+			c = e.opts.Codec(path, v)
+		}
+		key, value := NewData(k, e.opts.Binary, nil), NewData(v, e.opts.Binary, c)
+		r.Key, r.Value = &key, &value
+	}
+	for _, name := range path {
+		r.Bucket = append(r.Bucket, NewData([]byte(name), e.opts.Binary, nil))
+	}
+
+	var line []byte
+	var err error
+	if e.opts.Format == JSON {
+		line, err = json.MarshalIndent(r, "  ", "  ")
+	} else {
+		line, err = json.Marshal(r)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.opts.Format == JSONL:
+		line = append(line, '\n')
+	case e.count == 0:
+		line = append([]byte("\n  "), line...)
+	default:
+		line = append([]byte(",\n  "), line...)
+	}
+	e.count++
+	_, err = e.w.Write(line)
+	return err
+}
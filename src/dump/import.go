@@ -0,0 +1,283 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// Mode decides what happens to keys that already exist with another value
+type Mode int
+
+const (
+	Overwrite      Mode = iota // Replace the value
+	SkipExisting               // Keep the value
+	FailOnConflict             // Stop the import
+)
+
+func (m Mode) String() string {
+	switch m {
+	case SkipExisting:
+		return "skip-existing"
+	case FailOnConflict:
+		return "fail-on-conflict"
+	default:
+		return "overwrite"
+	}
+}
+
+// ParseMode returns the mode with the given name
+func ParseMode(name string) (Mode, error) {
+	for _, m := range []Mode{Overwrite, SkipExisting, FailOnConflict} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return Overwrite, fmt.Errorf("unknown mode %q, expected overwrite, skip-existing or fail-on-conflict", name)
+}
+
+// ChangeKind is what an import does to a bucket or key
+type ChangeKind int
+
+const (
+	CreateBucket ChangeKind = iota
+	CreateKey
+	UpdateKey
+	SkipKey // The key exists with another value, which is kept
+)
+
+// Change is a change made by an import, or that a dry run would make
+type Change struct {
+	Kind   ChangeKind
+	Bucket []string
+	Key    []byte
+}
+
+func (c Change) String() string {
+	path := bolt.PathString(c.Bucket)
+	switch c.Kind {
+	case CreateBucket:
+		return "create bucket " + path
+	case CreateKey:
+		return fmt.Sprintf("create key %s in %s", quoteKey(c.Key), path)
+	case UpdateKey:
+		return fmt.Sprintf("update key %s in %s", quoteKey(c.Key), path)
+	default:
+		return fmt.Sprintf("skip key %s in %s", quoteKey(c.Key), path)
+	}
+}
+
+// quoteKey shows a key as is when it is text, quoted with escapes otherwise
+func quoteKey(key []byte) string {
+	if codec.IsText(key) && !strings.ContainsAny(string(key), " \n\t\r") {
+		return string(key)
+	}
+	return strconv.Quote(string(key))
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	Format Format
+	Mode   Mode
+	// DryRun reads the database without writing to it
+	DryRun bool
+	// BatchSize is the number of records written per transaction, 1000
+	// when zero
+	BatchSize int
+	// Codec looks up the codecs that decoded values are encoded with,
+	// codec.Lookup when nil
+	Codec func(name string) (codec.Codec, error)
+	// Change is called for every change made, or that a dry run would make
+	Change func(Change)
+}
+
+// ImportResult counts what an import did
+type ImportResult struct {
+	Records   int
+	Buckets   int // Buckets created
+	Created   int // Keys created
+	Updated   int // Existing keys given a new value
+	Skipped   int // Existing keys with another value that were kept
+	Unchanged int // Existing keys that already had the value
+}
+
+// String summarises the result
+func (r ImportResult) String() string {
+	return fmt.Sprintf("%d records: %d buckets created, %d keys created, %d updated, %d skipped, %d unchanged",
+		r.Records, r.Buckets, r.Created, r.Updated, r.Skipped, r.Unchanged)
+}
+
+// Import writes the records read from r to the database, creating buckets
+// as needed. Records are written in batches of transactions, so a failed
+// import leaves the batches before the failure written; a dry run with
+// FailOnConflict finds conflicts without writing anything.
+func Import(db *bolt.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.Codec == nil {
+		opts.Codec = codec.Lookup
+	}
+	im := &importer{db: db, opts: opts, created: map[string]bool{}, written: map[string]map[string][]byte{}}
+	err := Read(r, opts.Format, func(rec Record) error {
+		if err := im.add(rec); err != nil {
+			return err
+		}
+		if len(im.batch) >= opts.BatchSize {
+			return im.flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = im.flush()
+	}
+	return im.result, err
+}
+
+// item is a record with its data encoded
+type item struct {
+	n      int // Number of the record in the input
+	bucket []string
+	key    []byte // nil for buckets
+	value  []byte
+}
+
+// importer writes records in batches
+type importer struct {
+	db      *bolt.DB
+	opts    ImportOptions
+	batch   []item
+	created map[string]bool              // Buckets a dry run would have created
+	written map[string]map[string][]byte // Values a dry run would have written, by bucket and key
+	result  ImportResult
+}
+
+// add encodes a record and queues it for the next batch
+func (im *importer) add(rec Record) error {
+	it := item{n: im.result.Records + 1}
+	for _, d := range rec.Bucket {
+		if err := d.resolve(im.opts.Codec); err != nil {
+			return err
+		}
+		it.bucket = append(it.bucket, string(d.Bytes))
+	}
+	if len(it.bucket) == 0 {
+		return fmt.Errorf("record has no bucket")
+	}
+	if rec.Key != nil {
+		if rec.Value == nil {
+			return fmt.Errorf("key has no value")
+		}
+		if err := rec.Key.resolve(im.opts.Codec); err != nil {
+			return err
+		}
+		if err := rec.Value.resolve(im.opts.Codec); err != nil {
+			return err
+		}
+		if len(rec.Key.Bytes) == 0 {
+			return fmt.Errorf("key cannot be empty")
+		}
+		it.key, it.value = rec.Key.Bytes, rec.Value.Bytes
+	}
+	im.batch = append(im.batch, it)
+	im.result.Records++
+	return nil
+}
+
+// flush writes the queued records in one transaction, or checks them in a
+// read transaction for dry runs
+func (im *importer) flush() error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	apply := func(tx *bolt.Tx) error {
+		for _, it := range im.batch {
+			if err := im.apply(tx, it); err != nil {
+				return fmt.Errorf("record %d: %v", it.n, err)
+			}
+		}
+		return nil
+	}
+	var err error
+	if im.opts.DryRun {
+		err = im.db.View(apply)
+	} else {
+		err = im.db.Update(apply)
+	}
+	im.batch = im.batch[:0]
+	return err
+}
+
+// apply writes an item, following the mode for existing keys
+func (im *importer) apply(tx *bolt.Tx, it item) error {
+	if err := im.ensureBucket(tx, it.bucket); err != nil || it.key == nil {
+		return err
+	}
+
+	old, exists, isBucket := tx.Get(it.bucket, it.key)
+	bucket := strings.Join(it.bucket, "\x00")
+	if value, ok := im.written[bucket][string(it.key)]; ok {
+		// A key given twice in the input exists by its second record
+		old, exists = value, true
+	}
+	kind := CreateKey
+	switch {
+	case isBucket:
+		return fmt.Errorf("key %s in %s is a bucket", quoteKey(it.key), bolt.PathString(it.bucket))
+	case exists && bytes.Equal(old, it.value):
+		im.result.Unchanged++
+		return nil
+	case exists && im.opts.Mode == FailOnConflict:
+		return fmt.Errorf("key %s in %s already exists with another value", quoteKey(it.key), bolt.PathString(it.bucket))
+	case exists && im.opts.Mode == SkipExisting:
+		im.result.Skipped++
+		im.report(Change{SkipKey, it.bucket, it.key})
+		return nil
+	case exists:
+		kind = UpdateKey
+		im.result.Updated++
+	default:
+		im.result.Created++
+	}
+	im.report(Change{kind, it.bucket, it.key})
+	if im.opts.DryRun {
+		if im.written[bucket] == nil {
+			im.written[bucket] = map[string][]byte{}
+		}
+		im.written[bucket][string(it.key)] = it.value
+		return nil
+	}
+	return tx.Put(it.bucket, it.key, it.value)
+}
+
+// ensureBucket creates the bucket at path and its parents, unless they exist
+func (im *importer) ensureBucket(tx *bolt.Tx, path []string) error {
+	for i := 1; i <= len(path); i++ {
+		prefix := path[:i]
+		if tx.HasBucket(prefix) || im.created[strings.Join(prefix, "\x00")] {
+			continue
+		}
+		im.result.Buckets++
+		im.report(Change{CreateBucket, prefix, nil})
+		if im.opts.DryRun {
+			im.created[strings.Join(prefix, "\x00")] = true
+			continue
+		}
+		if err := tx.CreateBucketIfNotExists(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// report passes a change to the callback, if any
+func (im *importer) report(c Change) {
+	if im.opts.Change != nil {
+		im.opts.Change(c)
+	}
+}
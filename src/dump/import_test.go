@@ -0,0 +1,126 @@
+package dump
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// importInput holds an unchanged key, a key with another value, a key given
+// twice in a new nested bucket and a new empty bucket
+const importInput = `{"bucket": ["users"], "key": "alice", "value": "1"}
+{"bucket": ["users"], "key": "bob", "value": "20"}
+{"bucket": ["users", "new"], "key": "carol", "value": "3"}
+{"bucket": ["users", "new"], "key": "carol", "value": "4"}
+{"bucket": ["logs"]}
+`
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      Mode
+		dryRun    bool
+		want      ImportResult
+		wantErr   bool
+		wantBob   string // Values once the import is over, "" when missing
+		wantCarol string
+	}{
+		{
+			name:    "overwrite",
+			mode:    Overwrite,
+			want:    ImportResult{Records: 5, Buckets: 2, Created: 1, Updated: 2, Unchanged: 1},
+			wantBob: "20", wantCarol: "4",
+		},
+		{
+			name:    "skip existing",
+			mode:    SkipExisting,
+			want:    ImportResult{Records: 5, Buckets: 2, Created: 1, Skipped: 2, Unchanged: 1},
+			wantBob: "2", wantCarol: "3",
+		},
+		{
+			name:    "fail on conflict",
+			mode:    FailOnConflict,
+			wantErr: true,
+			wantBob: "2",
+		},
+		{
+			name:    "overwrite dry run",
+			mode:    Overwrite,
+			dryRun:  true,
+			want:    ImportResult{Records: 5, Buckets: 2, Created: 1, Updated: 2, Unchanged: 1},
+			wantBob: "2",
+		},
+		{
+			name:    "skip existing dry run",
+			mode:    SkipExisting,
+			dryRun:  true,
+			want:    ImportResult{Records: 5, Buckets: 2, Created: 1, Skipped: 2, Unchanged: 1},
+			wantBob: "2",
+		},
+		{
+			name:    "fail on conflict dry run",
+			mode:    FailOnConflict,
+			dryRun:  true,
+			wantErr: true,
+			wantBob: "2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &bolt.DB{Path: filepath.Join(t.TempDir(), "test.db")}
+			if err := db.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if err := db.CreateBucket([]string{"users"}); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range map[string]string{"alice": "1", "bob": "2"} {
+				if err := db.PutValue([]string{"users"}, []byte(k), []byte(v)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var changes []Change
+			opts := ImportOptions{Format: JSONL, Mode: tt.mode, DryRun: tt.dryRun, Change: func(c Change) {
+				changes = append(changes, c)
+			}}
+			result, err := Import(db, strings.NewReader(importInput), opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result != tt.want {
+					t.Errorf("result = %+v, want %+v", result, tt.want)
+				}
+				if reported := len(changes); reported != tt.want.Buckets+tt.want.Created+tt.want.Updated+tt.want.Skipped {
+					t.Errorf("%d changes reported for %+v", reported, result)
+				}
+			}
+
+			get := func(path []string, key string) string {
+				v, _ := db.GetValue(path, []byte(key))
+				return string(v)
+			}
+			if bob := get([]string{"users"}, "bob"); bob != tt.wantBob {
+				t.Errorf("bob = %q, want %q", bob, tt.wantBob)
+			}
+			if carol := get([]string{"users", "new"}, "carol"); carol != tt.wantCarol {
+				t.Errorf("carol = %q, want %q", carol, tt.wantCarol)
+			}
+			buckets, err := db.GetBuckets(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wrote := len(buckets) > 1; wrote != (!tt.dryRun && !tt.wantErr) {
+				t.Errorf("buckets = %v after the import", buckets)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Read calls fn for every record of r in the given format, in order.
+// Decoded values are not encoded yet, see Import.
+//
+// CSV files start with a header naming their columns: bucket, the bucket
+// path with "/" between names, key and value, with optional key_encoding and
+// value_encoding columns giving how the cells are written, as base64, hex
+// or the name of a codec for values. Rows without a key are buckets.
+func Read(r io.Reader, format Format, fn func(Record) error) error {
+	switch format {
+	case JSON:
+		return readJSON(r, fn)
+	case CSV:
+		return readCSV(r, fn)
+	default:
+		return readJSONL(r, fn)
+	}
+}
+
+// readJSONL reads a record from every line that is not blank
+func readJSONL(r io.Reader, fn func(Record) error) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec Record
+			if uerr := json.Unmarshal(line, &rec); uerr != nil {
+				return fmt.Errorf("line %d: %v", n, uerr)
+			}
+			if ferr := fn(rec); ferr != nil {
+				return fmt.Errorf("line %d: %v", n, ferr)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readJSON reads the records of a JSON array one by one
+func readJSON(r io.Reader, fn func(Record) error) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array of records")
+	}
+	for n := 1; dec.More(); n++ {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			return fmt.Errorf("record %d: %v", n, err)
+		}
+		if err := fn(rec); err != nil {
+			return fmt.Errorf("record %d: %v", n, err)
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// readCSV reads a record from every row after the header
+func readCSV(r io.Reader, fn func(Record) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("could not read the CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"bucket", "key"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("CSV header has no %s column", name)
+		}
+	}
+	cell := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var rec Record
+		if bucket := cell(row, "bucket"); bucket != "" {
+			for _, name := range strings.Split(bucket, "/") {
+				rec.Bucket = append(rec.Bucket, Data{Bytes: []byte(name)})
+			}
+		}
+		if key := cell(row, "key"); key != "" {
+			k, err := encodedData(cell(row, "key_encoding"), key)
+			if err != nil {
+				return fmt.Errorf("row %d: key: %v", n, err)
+			}
+			v, err := encodedData(cell(row, "value_encoding"), cell(row, "value"))
+			if err != nil {
+				return fmt.Errorf("row %d: value: %v", n, err)
+			}
+			rec.Key, rec.Value = &k, &v
+		}
+		if err := fn(rec); err != nil {
+			return fmt.Errorf("row %d: %v", n, err)
+		}
+	}
+}
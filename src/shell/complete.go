@@ -0,0 +1,125 @@
+package shell
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// completeLimit is the number of completions listed at most
+const completeLimit = 200
+
+var errCompleteLimit = errors.New("too many completions")
+
+// Complete completes the word before pos in a command line: the command name
+// first, then bucket and key paths. It has the signature of a word completer
+// of github.com/peterh/liner.
+func (s *Shell) Complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	start := len(head)
+	for i := 0; i < len(head); {
+		if head[i] == ' ' || head[i] == '\t' {
+			i++
+			continue
+		}
+		_, n, err := scanWord(head[i:])
+		if err != nil {
+			// An open quote, left alone rather than guessed at
+			return head, nil, tail
+		}
+		if i+n == len(head) {
+			start = i
+		}
+		i += n
+	}
+	partial := head[start:]
+	words, _ := split(head[:start])
+	if len(words) == 0 {
+		var names []string
+		for _, c := range commands {
+			names = append(names, c.name)
+			if c.alias != "" {
+				names = append(names, c.alias)
+			}
+		}
+		names = append(names, "exit", "help")
+		sort.Strings(names)
+		for _, name := range names {
+			if strings.HasPrefix(name, partial) {
+				completions = append(completions, name+" ")
+			}
+		}
+		return head[:start], completions, tail
+	}
+
+	c, ok := lookup(string(words[0].text))
+	args := 0
+	for _, w := range words[1:] {
+		if !w.bare || !strings.HasPrefix(string(w.text), "-") {
+			args++
+		}
+	}
+	if !ok || args >= c.paths || strings.HasPrefix(partial, "-") {
+		return head, nil, tail
+	}
+	return head[:start], s.completePath(partial, c.dirs), tail
+}
+
+// completePath lists the entries of the bucket named by the start of
+// partial whose name starts with the rest of it, which must be bare
+func (s *Shell) completePath(partial string, dirs bool) []string {
+	dir, prefix := "", partial
+	if i := lastSlash(partial); i >= 0 {
+		dir, prefix = partial[:i+1], partial[i+1:]
+	}
+	if strings.ContainsAny(prefix, `"'\`) {
+		return nil
+	}
+	w, _, err := scanWord(dir)
+	if err != nil {
+		return nil
+	}
+	path := s.resolve(w)
+
+	var completions []string
+	s.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(path, []byte(prefix), func(k, v []byte) error {
+			if len(completions) == completeLimit {
+				return errCompleteLimit
+			}
+			switch {
+			case v == nil:
+				completions = append(completions, dir+format(k)+"/")
+			case !dirs:
+				completions = append(completions, dir+format(k)+" ")
+			}
+			return nil
+		})
+	})
+	return completions
+}
+
+// lastSlash returns the index of the last slash outside quotes in a word, or
+// -1
+func lastSlash(s string) int {
+	last := -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\'':
+			if end := strings.IndexByte(s[i+1:], '\''); end >= 0 {
+				i += end + 1
+			}
+		case '"':
+			if end := closingQuote(s, i); end >= 0 {
+				i = end
+			}
+		case '/':
+			last = i
+		}
+	}
+	return last
+}
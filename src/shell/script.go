@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChangeKind is what a command of a script changed
+type ChangeKind int
+
+const (
+	CreateKey ChangeKind = iota
+	UpdateKey
+	DeleteKey
+	CreateBucket
+	DeleteBucket
+	Move
+	Copy
+)
+
+// Change is a key or bucket changed by a command of a script
+type Change struct {
+	Kind ChangeKind
+	Path []string
+	To   []string // Destination of moves and copies
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case CreateKey:
+		return "create key " + formatPath(c.Path)
+	case UpdateKey:
+		return "update key " + formatPath(c.Path)
+	case DeleteKey:
+		return "delete key " + formatPath(c.Path)
+	case CreateBucket:
+		return "create bucket " + formatPath(c.Path)
+	case DeleteBucket:
+		return "delete bucket " + formatPath(c.Path)
+	case Move:
+		return fmt.Sprintf("move %s → %s", formatPath(c.Path), formatPath(c.To))
+	default:
+		return fmt.Sprintf("copy %s → %s", formatPath(c.Path), formatPath(c.To))
+	}
+}
+
+// Exec runs the commands read from r, one per line, in a single
+// transaction. The transaction is committed when every command succeeds and
+// dryRun is not set, and rolled back otherwise. Exec returns the changes the
+// commands made, and stops at the first failing command with an error naming
+// its line in the script called name.
+func (s *Shell) Exec(r io.Reader, name string, dryRun bool) ([]Change, error) {
+	if err := s.begin("", nil); err != nil {
+		return nil, err
+	}
+	s.script, s.changes = true, nil
+	defer func() { s.script = false }()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for n := 1; scanner.Scan(); n++ {
+		err := s.Run(scanner.Text())
+		if errors.Is(err, ErrExit) {
+			break
+		}
+		if err != nil {
+			s.rollback("", nil)
+			return s.changes, fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.rollback("", nil)
+		return s.changes, fmt.Errorf("could not read %s: %v", name, err)
+	}
+	if dryRun {
+		return s.changes, s.rollback("", nil)
+	}
+	return s.changes, s.commit("", nil)
+}
@@ -0,0 +1,107 @@
+package shell
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+)
+
+// openTestDB returns a database holding users/alice
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db := &bolt.DB{Path: filepath.Join(t.TempDir(), "test.db")}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.CreateBucket([]string{"users"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutValue([]string{"users"}, []byte("alice"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		dryRun      bool
+		wantErr     string // Part of the error, empty when the script succeeds
+		wantChanges []string
+		wantBob     string // Value of users/bob afterwards, "" when missing
+		wantAlice   string
+	}{
+		{
+			name:        "committed",
+			script:      "cd users\nput bob 2\nrm alice\n",
+			wantChanges: []string{"create key /users/bob", "delete key /users/alice"},
+			wantBob:     "2",
+		},
+		{
+			name:        "dry run",
+			script:      "put users/bob 2\nrm users/alice\n",
+			dryRun:      true,
+			wantChanges: []string{"create key /users/bob", "delete key /users/alice"},
+			wantAlice:   "1",
+		},
+		{
+			name:        "failing command",
+			script:      "put users/bob 2\n# comment\nrm users/carol\nrm users/alice\n",
+			wantErr:     "script:3: /users/carol not found",
+			wantChanges: []string{"create key /users/bob"},
+			wantAlice:   "1",
+		},
+		{
+			name:        "transaction commands",
+			script:      "put users/bob 2\nbegin\n",
+			wantErr:     "script:2: begin cannot be used in scripts",
+			wantChanges: []string{"create key /users/bob"},
+			wantAlice:   "1",
+		},
+		{
+			name:        "exit",
+			script:      "put users/bob 2\nexit\nrm users/alice\n",
+			wantChanges: []string{"create key /users/bob"},
+			wantBob:     "2",
+			wantAlice:   "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			s := New(db, io.Discard)
+			changes, err := s.Exec(strings.NewReader(tt.script), "script", tt.dryRun)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if s.InTx() {
+				t.Error("the transaction was left open")
+			}
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantChanges, "\n") {
+				t.Errorf("changes = %q, want %q", got, tt.wantChanges)
+			}
+			for key, want := range map[string]string{"bob": tt.wantBob, "alice": tt.wantAlice} {
+				value, err := db.GetValue([]string{"users"}, []byte(key))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(value) != want {
+					t.Errorf("%s = %q, want %q", key, value, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,535 @@
+// Package shell runs line-oriented commands on a BoltDB file, for quick
+// fixes where the TUI is too slow, such as over SSH
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lunargon/bolt-tui/src/bolt"
+	"github.com/lunargon/bolt-tui/src/codec"
+)
+
+// ErrExit is returned by Run when the session should end
+var ErrExit = errors.New("exit")
+
+// Shell runs commands on a database. Paths are relative to the current
+// bucket unless they start with a slash, and name a bucket or the key of a
+// bucket. Each command runs in a transaction of its own, unless one was
+// started with begin.
+type Shell struct {
+	db  *bolt.DB
+	out io.Writer
+	cwd []string // Current bucket, the root when empty
+	tx  *bolt.Tx // Transaction started with begin, nil outside one
+
+	script  bool     // Running a script with Exec
+	changes []Change // Changes made by the script so far
+	pending []Change // Changes made by the running command
+}
+
+// New returns a shell on an open database that prints to out
+func New(db *bolt.DB, out io.Writer) *Shell {
+	return &Shell{db: db, out: out}
+}
+
+// command is a command of the shell
+type command struct {
+	name  string
+	alias string // Other name, for the verbs of scripts
+	args  string // Arguments shown in the help
+	help  string
+	min   int // Arguments required
+	max   int
+	flags string // Single letter flags accepted
+	paths int    // Leading arguments completed as paths
+	dirs  bool   // Complete buckets only
+	run   func(s *Shell, flags string, args []word) error
+}
+
+var commands = []command{
+	{name: "cd", args: "[bucket]", help: "change the current bucket, the root without argument", max: 1, paths: 1, dirs: true, run: (*Shell).cd},
+	{name: "pwd", help: "print the current bucket", run: (*Shell).pwd},
+	{name: "ls", args: "[-l] [bucket]", help: "list keys and buckets, with sizes with -l", max: 1, flags: "l", paths: 1, dirs: true, run: (*Shell).ls},
+	{name: "get", args: "<key>", help: "print a value", min: 1, max: 1, paths: 1, run: (*Shell).get},
+	{name: "put", args: "<key> <value>", help: "set a value", min: 2, max: 2, paths: 1, run: (*Shell).put},
+	{name: "rm", alias: "delete", args: "[-r] <key>", help: "delete a key, or a bucket and its contents with -r", min: 1, max: 1, flags: "r", paths: 1, run: (*Shell).rm},
+	{name: "mkbucket", args: "<bucket>", help: "create a bucket and any missing parents", min: 1, max: 1, paths: 1, dirs: true, run: (*Shell).mkbucket},
+	{name: "mv", alias: "rename", args: "<from> <to>", help: "rename or move a key or bucket", min: 2, max: 2, paths: 2, run: (*Shell).mv},
+	{name: "cp", alias: "copy", args: "<from> <to>", help: "copy a key, or a bucket and its contents", min: 2, max: 2, paths: 2, run: (*Shell).cp},
+	{name: "stat", args: "<key>", help: "describe a key or bucket", min: 1, max: 1, paths: 1, run: (*Shell).stat},
+	{name: "begin", help: "start a transaction, so that changes are only written by commit", run: (*Shell).begin},
+	{name: "commit", help: "write the changes of the transaction", run: (*Shell).commit},
+	{name: "rollback", help: "discard the changes of the transaction", run: (*Shell).rollback},
+}
+
+// lookup returns the command called name
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name || c.alias == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// Prompt returns the prompt, with the current bucket and whether a
+// transaction is open
+func (s *Shell) Prompt() string {
+	if s.tx != nil {
+		return formatPath(s.cwd) + " (tx)> "
+	}
+	return formatPath(s.cwd) + "> "
+}
+
+// InTx reports whether a transaction started with begin is open
+func (s *Shell) InTx() bool {
+	return s.tx != nil
+}
+
+// Run runs a command line. Empty lines and lines starting with # do
+// nothing.
+func (s *Shell) Run(line string) error {
+	words, err := split(line)
+	if err != nil || len(words) == 0 || strings.HasPrefix(string(words[0].text), "#") {
+		return err
+	}
+	name := string(words[0].text)
+	switch name {
+	case "exit", "quit":
+		return ErrExit
+	case "help":
+		s.help()
+		return nil
+	case "begin", "commit", "rollback":
+		if s.script {
+			return fmt.Errorf("%s cannot be used in scripts, which run in one transaction", name)
+		}
+	}
+
+	c, ok := lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown command %s, see help", name)
+	}
+	var flags string
+	args := words[1:]
+	for len(args) > 0 && args[0].bare && len(args[0].text) > 1 && args[0].text[0] == '-' {
+		for _, f := range string(args[0].text[1:]) {
+			if !strings.ContainsRune(c.flags, f) {
+				return fmt.Errorf("%s: unknown flag -%c", name, f)
+			}
+			flags += string(f)
+		}
+		args = args[1:]
+	}
+	if len(args) < c.min || len(args) > c.max {
+		return fmt.Errorf("usage: %s %s", c.name, c.args)
+	}
+	return c.run(s, flags, args)
+}
+
+// help prints the commands
+func (s *Shell) help() {
+	w := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		name := c.name
+		if c.alias != "" {
+			name += ", " + c.alias
+		}
+		fmt.Fprintf(w, "%s %s\t%s\n", name, c.args, c.help)
+	}
+	fmt.Fprintf(w, "exit\tleave the shell, discarding an open transaction\n")
+	w.Flush()
+	fmt.Fprintln(s.out, `
+Paths are relative to the current bucket unless they start with "/", and ".."
+is the parent bucket. Names with spaces or slashes are quoted, with '...' as
+is or "..." with Go escapes, and 0x followed by hex digits stands for binary
+data.`)
+}
+
+// view runs fn in the open transaction, or in a read-only one
+func (s *Shell) view(fn func(tx *bolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.View(fn)
+}
+
+// update runs fn in the open transaction, or in a read-write one. The
+// changes fn records are kept when it succeeds. Commands record a change
+// once they are done checking and start writing it, so when fn fails after
+// recording one the open transaction may hold part of the command's writes,
+// and is rolled back as a whole.
+func (s *Shell) update(fn func(tx *bolt.Tx) error) error {
+	s.pending = s.pending[:0]
+	if s.tx == nil {
+		err := s.db.Update(fn)
+		if err == nil && s.script {
+			s.changes = append(s.changes, s.pending...)
+		}
+		return err
+	}
+	err := fn(s.tx)
+	switch {
+	case err == nil && s.script:
+		s.changes = append(s.changes, s.pending...)
+	case err != nil && len(s.pending) > 0:
+		if rerr := s.rollback("", nil); rerr != nil {
+			return fmt.Errorf("%v, and the transaction could not be rolled back: %v", err, rerr)
+		}
+		return fmt.Errorf("%v, the transaction was rolled back as the command failed partway", err)
+	}
+	return err
+}
+
+// record notes a change the running command is about to write
+func (s *Shell) record(kind ChangeKind, path, to []string) {
+	s.pending = append(s.pending, Change{Kind: kind, Path: path, To: to})
+}
+
+// resolve returns the absolute path named by a word
+func (s *Shell) resolve(w word) []string {
+	var path []string
+	if !w.abs {
+		path = append(path, s.cwd...)
+	}
+	for _, p := range w.parts {
+		switch {
+		case p.bare && string(p.name) == ".":
+		case p.bare && string(p.name) == "..":
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		default:
+			path = append(path, string(p.name))
+		}
+	}
+	return path
+}
+
+// entry looks up what a path names. The root and top-level entries are
+// buckets.
+func entry(tx *bolt.Tx, path []string) (value []byte, exists, isBucket bool) {
+	switch len(path) {
+	case 0:
+		return nil, true, true
+	case 1:
+		ok := tx.HasBucket(path)
+		return nil, ok, ok
+	}
+	return tx.Get(path[:len(path)-1], []byte(path[len(path)-1]))
+}
+
+// splitKey separates a path into its bucket and key
+func splitKey(path []string) ([]string, []byte) {
+	return path[:len(path)-1], []byte(path[len(path)-1])
+}
+
+func (s *Shell) cd(_ string, args []word) error {
+	var path []string
+	if len(args) > 0 {
+		path = s.resolve(args[0])
+	}
+	return s.view(func(tx *bolt.Tx) error {
+		if _, exists, isBucket := entry(tx, path); !isBucket {
+			return notBucket(path, exists)
+		}
+		s.cwd = path
+		return nil
+	})
+}
+
+func (s *Shell) pwd(string, []word) error {
+	fmt.Fprintln(s.out, formatPath(s.cwd))
+	return nil
+}
+
+func (s *Shell) ls(flags string, args []word) error {
+	path := s.cwd
+	if len(args) > 0 {
+		path = s.resolve(args[0])
+	}
+	long := strings.Contains(flags, "l")
+	return s.view(func(tx *bolt.Tx) error {
+		if _, exists, isBucket := entry(tx, path); !isBucket {
+			return notBucket(path, exists)
+		}
+		w := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		err := tx.ForEach(path, nil, func(k, v []byte) error {
+			name := format(k)
+			switch {
+			case v == nil && long:
+				fmt.Fprintf(w, "bucket\t  %s/\n", name)
+			case v == nil:
+				fmt.Fprintf(s.out, "%s/\n", name)
+			case long:
+				fmt.Fprintf(w, "%d\t  %s\n", len(v), name)
+			default:
+				fmt.Fprintln(s.out, name)
+			}
+			return nil
+		})
+		w.Flush()
+		return err
+	})
+}
+
+func (s *Shell) get(_ string, args []word) error {
+	path := s.resolve(args[0])
+	return s.view(func(tx *bolt.Tx) error {
+		value, exists, isBucket := entry(tx, path)
+		switch {
+		case isBucket:
+			return fmt.Errorf("%s is a bucket", formatPath(path))
+		case !exists:
+			return fmt.Errorf("%s not found", formatPath(path))
+		}
+		if codec.IsText(value) {
+			fmt.Fprintln(s.out, string(value))
+		} else {
+			fmt.Fprintln(s.out, format(value))
+		}
+		return nil
+	})
+}
+
+func (s *Shell) put(_ string, args []word) error {
+	path := s.resolve(args[0])
+	if len(path) < 2 {
+		return fmt.Errorf("the root only holds buckets, use mkbucket")
+	}
+	value := args[1].text
+	return s.update(func(tx *bolt.Tx) error {
+		old, exists, isBucket := entry(tx, path)
+		switch {
+		case isBucket:
+			return fmt.Errorf("%s is a bucket", formatPath(path))
+		case exists && bytes.Equal(old, value):
+			return nil
+		}
+		bucket, key := splitKey(path)
+		if !tx.HasBucket(bucket) {
+			return fmt.Errorf("bucket %s not found", formatPath(bucket))
+		}
+		if exists {
+			s.record(UpdateKey, path, nil)
+		} else {
+			s.record(CreateKey, path, nil)
+		}
+		return tx.Put(bucket, key, value)
+	})
+}
+
+func (s *Shell) rm(flags string, args []word) error {
+	path := s.resolve(args[0])
+	if len(path) == 0 {
+		return fmt.Errorf("cannot remove the root")
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		_, exists, isBucket := entry(tx, path)
+		switch {
+		case !exists:
+			return fmt.Errorf("%s not found", formatPath(path))
+		case isBucket && !strings.Contains(flags, "r"):
+			return fmt.Errorf("%s is a bucket, use rm -r", formatPath(path))
+		case isBucket:
+			s.record(DeleteBucket, path, nil)
+			return tx.DeleteBucket(path)
+		}
+		s.record(DeleteKey, path, nil)
+		bucket, key := splitKey(path)
+		return tx.Delete(bucket, key)
+	})
+}
+
+func (s *Shell) mkbucket(_ string, args []word) error {
+	path := s.resolve(args[0])
+	return s.update(func(tx *bolt.Tx) error {
+		if _, exists, isBucket := entry(tx, path); exists && !isBucket {
+			return fmt.Errorf("%s is a key", formatPath(path))
+		} else if !exists {
+			s.record(CreateBucket, path, nil)
+		}
+		return tx.CreateBucketIfNotExists(path)
+	})
+}
+
+func (s *Shell) mv(_ string, args []word) error {
+	return s.transfer(args[0], args[1], true)
+}
+
+func (s *Shell) cp(_ string, args []word) error {
+	return s.transfer(args[0], args[1], false)
+}
+
+// transfer copies a key or bucket, and deletes the original when moving.
+// Copying into an existing bucket keeps the name, and existing keys are
+// never overwritten. Everything is checked before the first write.
+func (s *Shell) transfer(fromArg, toArg word, move bool) error {
+	from, to := s.resolve(fromArg), s.resolve(toArg)
+	if len(from) == 0 {
+		return fmt.Errorf("cannot copy the root")
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		value, exists, isBucket := entry(tx, from)
+		if !exists {
+			return fmt.Errorf("%s not found", formatPath(from))
+		}
+		if _, _, toBucket := entry(tx, to); toBucket {
+			to = append(to[:len(to):len(to)], from[len(from)-1])
+		}
+		if _, exists, _ := entry(tx, to); exists {
+			return fmt.Errorf("%s already exists", formatPath(to))
+		}
+		parent := to[:len(to)-1]
+		if _, _, ok := entry(tx, parent); !ok {
+			return fmt.Errorf("bucket %s not found", formatPath(parent))
+		}
+		switch {
+		case isBucket && isPrefix(from, to):
+			return fmt.Errorf("cannot copy %s into itself", formatPath(from))
+		case !isBucket && len(parent) == 0:
+			return fmt.Errorf("the root only holds buckets")
+		}
+		if move {
+			s.record(Move, from, to)
+		} else {
+			s.record(Copy, from, to)
+		}
+
+		if isBucket {
+			if err := tx.CopyBucket(from, to); err != nil {
+				return err
+			}
+			if move {
+				return tx.DeleteBucket(from)
+			}
+			return nil
+		}
+
+		bucket, key := splitKey(to)
+		if err := tx.Put(bucket, key, append([]byte{}, value...)); err != nil {
+			return err
+		}
+		if move {
+			bucket, key := splitKey(from)
+			return tx.Delete(bucket, key)
+		}
+		return nil
+	})
+}
+
+// isPrefix reports whether path starts with prefix
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Shell) stat(_ string, args []word) error {
+	path := s.resolve(args[0])
+	return s.view(func(tx *bolt.Tx) error {
+		value, exists, isBucket := entry(tx, path)
+		if !exists {
+			return fmt.Errorf("%s not found", formatPath(path))
+		}
+		w := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintf(w, "path:\t%s\n", formatPath(path))
+		if !isBucket {
+			fmt.Fprintf(w, "type:\tkey, %s\n", kind(value))
+			fmt.Fprintf(w, "size:\t%d bytes\n", len(value))
+			return nil
+		}
+		if len(path) == 0 {
+			fmt.Fprintf(w, "type:\troot\n")
+			return nil
+		}
+		stats, err := tx.BucketStats(path)
+		if err != nil {
+			return err
+		}
+		sequence, err := tx.Sequence(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "type:\tbucket\n")
+		fmt.Fprintf(w, "keys:\t%d, nested buckets included\n", stats.Keys)
+		fmt.Fprintf(w, "buckets:\t%d nested\n", stats.Buckets)
+		fmt.Fprintf(w, "depth:\t%d\n", stats.Depth)
+		fmt.Fprintf(w, "size:\t%s in use of %s in %d pages\n", bolt.FormatSize(stats.InUse), bolt.FormatSize(stats.Alloc), stats.Pages)
+		fmt.Fprintf(w, "sequence:\t%d\n", sequence)
+		return nil
+	})
+}
+
+// kind describes a value
+func kind(v []byte) string {
+	switch {
+	case len(v) == 0:
+		return "empty"
+	case json.Valid(v):
+		return "JSON"
+	case codec.IsText(v):
+		return "text"
+	default:
+		return "binary"
+	}
+}
+
+func (s *Shell) begin(string, []word) error {
+	if s.tx != nil {
+		return fmt.Errorf("a transaction is already open")
+	}
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return fmt.Errorf("could not begin a transaction: %v", err)
+	}
+	s.tx = tx
+	return nil
+}
+
+func (s *Shell) commit(string, []word) error {
+	if s.tx == nil {
+		return fmt.Errorf("no transaction is open")
+	}
+	tx := s.tx
+	s.tx = nil
+	return tx.Commit()
+}
+
+func (s *Shell) rollback(string, []word) error {
+	if s.tx == nil {
+		return fmt.Errorf("no transaction is open")
+	}
+	tx := s.tx
+	s.tx = nil
+	return tx.Rollback()
+}
+
+// Close discards the open transaction, if any
+func (s *Shell) Close() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.rollback("", nil)
+}
+
+// notBucket is the error for a path that is expected to be a bucket
+func notBucket(path []string, exists bool) error {
+	if exists {
+		return fmt.Errorf("%s is not a bucket", formatPath(path))
+	}
+	return fmt.Errorf("bucket %s not found", formatPath(path))
+}
@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunInTransaction(t *testing.T) {
+	long := strings.Repeat("x", 40000) // Longer than bolt allows keys to be
+
+	tests := []struct {
+		name    string
+		lines   []string // Run after begin and put users/bob 2
+		wantErr string   // Part of the error of the last line
+		wantTx  bool     // The transaction is still open
+		wantBob string   // Value of users/bob once the transaction is over
+	}{
+		{
+			name:    "failed check keeps the transaction",
+			lines:   []string{"rm users/carol"},
+			wantErr: "/users/carol not found",
+			wantTx:  true,
+			wantBob: "2",
+		},
+		{
+			name:    "copy into itself writes nothing",
+			lines:   []string{"mkbucket users/sub", "cp users users/sub"},
+			wantErr: "cannot copy /users into itself",
+			wantTx:  true,
+			wantBob: "2",
+		},
+		{
+			name:    "key copied to the root writes nothing",
+			lines:   []string{"cp users/alice /x"},
+			wantErr: "the root only holds buckets",
+			wantTx:  true,
+			wantBob: "2",
+		},
+		{
+			name:    "failed write rolls back",
+			lines:   []string{"put users/" + long + " 1"},
+			wantErr: "the transaction was rolled back",
+		},
+		{
+			name:    "failed copy rolls back",
+			lines:   []string{"mkbucket other", "cp users/alice other/" + long},
+			wantErr: "the transaction was rolled back",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			s := New(db, io.Discard)
+			t.Cleanup(func() { s.Close() })
+			lines := append([]string{"begin", "put users/bob 2"}, tt.lines...)
+			var err error
+			for i, line := range lines {
+				err = s.Run(line)
+				if i < len(lines)-1 && err != nil {
+					t.Fatalf("%s: %v", line, err)
+				}
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if s.InTx() != tt.wantTx {
+				t.Fatalf("transaction open = %v, want %v", s.InTx(), tt.wantTx)
+			}
+			if tt.wantTx {
+				if err := s.Run("commit"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			bob, err := db.GetValue([]string{"users"}, []byte("bob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bob) != tt.wantBob {
+				t.Errorf("bob = %q, want %q", bob, tt.wantBob)
+			}
+		})
+	}
+}
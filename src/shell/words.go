@@ -0,0 +1,178 @@
+package shell
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// word is an argument of a command line
+type word struct {
+	text  []byte // The whole argument, unquoted
+	parts []part // The argument split at the slashes outside quotes
+	abs   bool   // The argument starts with a slash
+	bare  bool   // The argument has no quotes or escapes
+}
+
+// part is a bucket or key name of a path
+type part struct {
+	name []byte
+	bare bool // Bare "." and ".." stand for the current and parent bucket
+}
+
+// split breaks a command line into words. Words are separated by spaces.
+// Double quotes hold a Go string with escapes, single quotes hold text as is
+// and a backslash escapes the next character. A bare word or part of a path
+// made of 0x and hex digits stands for the bytes it encodes, so that binary
+// keys and values can be typed.
+func split(line string) ([]word, error) {
+	var words []word
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		w, n, err := scanWord(line[i:])
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+		i += n
+	}
+	return words, nil
+}
+
+// scanWord reads the word at the start of s, and returns its length in s
+func scanWord(s string) (word, int, error) {
+	w := word{bare: true, abs: strings.HasPrefix(s, "/")}
+	var text, name []byte
+	nameBare := true
+	endPart := func() {
+		if len(name) > 0 || !nameBare {
+			w.parts = append(w.parts, part{decodeHex(name, nameBare), nameBare})
+		}
+		name, nameBare = nil, true
+	}
+
+	i := 0
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		switch c := s[i]; c {
+		case '"':
+			end := closingQuote(s, i)
+			if end < 0 {
+				return word{}, 0, fmt.Errorf("missing closing quote in %s", s[i:])
+			}
+			unquoted, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return word{}, 0, fmt.Errorf("invalid quoted string %s: %v", s[i:end+1], err)
+			}
+			text, name = append(text, unquoted...), append(name, unquoted...)
+			w.bare, nameBare = false, false
+			i = end + 1
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return word{}, 0, fmt.Errorf("missing closing quote in %s", s[i:])
+			}
+			quoted := s[i+1 : i+1+end]
+			text, name = append(text, quoted...), append(name, quoted...)
+			w.bare, nameBare = false, false
+			i += end + 2
+		case '\\':
+			if i+1 == len(s) {
+				return word{}, 0, fmt.Errorf("nothing to escape at the end of the line")
+			}
+			text, name = append(text, s[i+1]), append(name, s[i+1])
+			w.bare, nameBare = false, false
+			i += 2
+		case '/':
+			text = append(text, c)
+			endPart()
+			i++
+		default:
+			text, name = append(text, c), append(name, c)
+			i++
+		}
+	}
+	endPart()
+	w.text = decodeHex(text, w.bare)
+	return w, i, nil
+}
+
+// closingQuote returns the index of the double quote closing the one at
+// start, or -1
+func closingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeHex decodes bare 0x-prefixed hex, and returns anything else as is
+func decodeHex(b []byte, bare bool) []byte {
+	if !bare || len(b) < 3 || string(b[:2]) != "0x" {
+		return b
+	}
+	decoded, err := hex.DecodeString(string(b[2:]))
+	if err != nil {
+		return b
+	}
+	return decoded
+}
+
+// format returns the form of a name or value that split reads back as the
+// same bytes: bare when that is unambiguous, a Go quoted string when the
+// bytes are mostly text, and 0x-prefixed hex otherwise
+func format(b []byte) string {
+	if isBare(b) {
+		return string(b)
+	}
+	if utf8.Valid(b) {
+		printable := 0
+		for _, r := range string(b) {
+			if unicode.IsPrint(r) {
+				printable++
+			}
+		}
+		if printable*4 >= utf8.RuneCount(b)*3 {
+			return strconv.Quote(string(b))
+		}
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// isBare reports whether b can be typed without quotes
+func isBare(b []byte) bool {
+	s := string(b)
+	if s == "" || s == "." || s == ".." || strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "-") || !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) || strings.ContainsRune(`"'\/`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPath returns the form of a bucket path that split reads back, with
+// a leading slash
+func formatPath(path []string) string {
+	var s strings.Builder
+	for _, name := range path {
+		s.WriteString("/")
+		s.WriteString(format([]byte(name)))
+	}
+	if s.Len() == 0 {
+		return "/"
+	}
+	return s.String()
+}